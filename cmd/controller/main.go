@@ -19,11 +19,12 @@ package main
 import (
 	// The set of controllers this controller process runs.
 	"knative.dev/net-contour/pkg/reconciler/contour"
+	"knative.dev/net-contour/pkg/reconciler/delegation"
 
 	// This defines the shared main for injected controllers.
 	"knative.dev/pkg/injection/sharedmain"
 )
 
 func main() {
-	sharedmain.Main("net-contour-controller", contour.NewController)
+	sharedmain.Main("net-contour-controller", contour.NewController, contour.NewFinalizerController, delegation.NewController)
 }
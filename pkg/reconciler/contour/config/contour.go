@@ -17,14 +17,20 @@ limitations under the License.
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"strings"
 	"time"
 
 	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/configmap"
@@ -39,9 +45,43 @@ const (
 	visibilityConfigKey = "visibility"
 	//nolint:gosec // Not an actual secret.
 	defaultTLSSecretConfigKey = "default-tls-secret"
+	defaultTLSSecretsKey      = "default-tls-secrets"
 	timeoutPolicyIdleKey      = "timeout-policy-idle"
 	timeoutPolicyResponseKey  = "timeout-policy-response"
 	corsPolicy                = "cors-policy"
+	allowedBackendNamespaces  = "allowed-backend-namespaces"
+	retryPolicyConfigKey      = "retry-policy"
+	localRateLimitKey         = "local-rate-limit"
+	localRateLimitVisKey      = "local-rate-limit-visibilities"
+	globalRateLimitKey        = "global-rate-limit-descriptors"
+	ipFilterPolicyKey         = "ip-filter-policy"
+	jwtProvidersKey           = "jwt-providers"
+	defaultAuthorizationKey   = "default-authorization"
+	downstreamTLSKey          = "downstream-tls"
+	ingressClassModeKey       = "ingress-class-mode"
+	rootProxyNamespaceKey     = "root-proxy-namespace"
+)
+
+var (
+	// validRetryOn holds the retry conditions supported by Contour.
+	validRetryOn = sets.New[v1.RetryOn](
+		"5xx",
+		"gateway-error",
+		"reset",
+		"reset-before-request",
+		"connect-failure",
+		"envoy-ratelimited",
+		"retriable-4xx",
+		"refused-stream",
+		"retriable-status-codes",
+		"retriable-headers",
+		"http3-post-connect-failure",
+		"cancelled",
+		"deadline-exceeded",
+		"internal",
+		"resource-exhausted",
+		"unavailable",
+	)
 )
 
 // Contour contains contour related configuration defined in the
@@ -53,11 +93,251 @@ type Contour struct {
 	TimeoutPolicyResponse string
 	TimeoutPolicyIdle     string
 	CORSPolicy            *v1.CORSPolicy
+	// DefaultTLSSecrets maps domains, and wildcard domains written as "*.suffix"
+	// that match a single label, to the default TLS secret of the external hosts
+	// they match.  They take precedence over DefaultTLSSecret.
+	DefaultTLSSecrets map[string]types.NamespacedName
+	// AllowedBackendNamespaces holds the namespaces other than its own that a
+	// KIngress may route to.  The entry "*" allows every namespace.
+	AllowedBackendNamespaces sets.Set[string]
+	// VisibilityAddresses holds how the load balancer addresses reported in
+	// the KIngress status are determined for each visibility.
+	VisibilityAddresses map[v1alpha1.IngressVisibility]LoadBalancerAddress
+	// VisibilityProbePorts holds the ports of the Envoy services probed for
+	// each visibility that doesn't use the default ones.
+	VisibilityProbePorts map[v1alpha1.IngressVisibility]ProbePorts
+	// RetryPolicy is applied to every route, unless overridden on the KIngress.
+	// When nil, the built-in default policy is used.
+	RetryPolicy *v1.RetryPolicy
+	// LocalRateLimit is applied to the routes of the visibilities in
+	// LocalRateLimitVisibilities, unless overridden on the KIngress.
+	LocalRateLimit             *v1.LocalRateLimitPolicy
+	LocalRateLimitVisibilities sets.Set[v1alpha1.IngressVisibility]
+	// GlobalRateLimitDescriptors are sent to the global rate limit service for
+	// the external virtual hosts, unless overridden on the KIngress.
+	GlobalRateLimitDescriptors []v1.RateLimitDescriptor
+	// IPFilters holds the default IP filter for the routes of each visibility.
+	IPFilters map[v1alpha1.IngressVisibility]IPFilter
+	// JWTProviders holds the JWT providers that a KIngress may require, by name.
+	JWTProviders map[string]v1.JWTProvider
+	// DefaultAuthorization is the authorization server of the external TLS virtual
+	// hosts whose KIngress neither has its own nor opts out.
+	DefaultAuthorization *v1.AuthorizationServer
+	// DownstreamTLS holds the default TLS settings of the external virtual hosts
+	// that terminate TLS.
+	DownstreamTLS *DownstreamTLS
+	// IngressClassMode selects how HTTPProxies are assigned their Contour class.
+	IngressClassMode IngressClassMode
+	// RootProxyNamespace, when set, holds the root HTTPProxy of every host, which
+	// includes the non-root HTTPProxy of its KIngress.
+	RootProxyNamespace string
+}
+
+// IngressClassMode selects how HTTPProxies are assigned their Contour class.
+type IngressClassMode string
+
+const (
+	// IngressClassModeAnnotation sets the projectcontour.io/ingress.class annotation.
+	IngressClassModeAnnotation IngressClassMode = "annotation"
+	// IngressClassModeField sets Spec.IngressClassName.
+	IngressClassModeField IngressClassMode = "field"
+	// IngressClassModeBoth sets both the annotation and Spec.IngressClassName.
+	IngressClassModeBoth IngressClassMode = "both"
+)
+
+// UsesAnnotation returns whether the class annotation is set, which is the default.
+func (m IngressClassMode) UsesAnnotation() bool {
+	return m != IngressClassModeField
+}
+
+// UsesField returns whether Spec.IngressClassName is set.
+func (m IngressClassMode) UsesField() bool {
+	return m == IngressClassModeField || m == IngressClassModeBoth
+}
+
+// IPFilter allows or denies requests based on the IP address they come from.
+type IPFilter struct {
+	// Allow and Deny hold CIDRs or bare IP addresses. At most one of them may be set.
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+	// Source selects the address that is filtered on, Remote by default.
+	Source v1.IPFilterSource `json:"source,omitempty"`
+}
+
+// Validate checks the CIDRs and source of the filter.
+func (f *IPFilter) Validate() error {
+	if len(f.Allow) != 0 && len(f.Deny) != 0 {
+		return errors.New("only one of allow and deny may be set")
+	}
+	switch f.Source {
+	case "", v1.IPFilterSourcePeer, v1.IPFilterSourceRemote:
+	default:
+		return fmt.Errorf("source %q is invalid, must be %s or %s", f.Source, v1.IPFilterSourcePeer, v1.IPFilterSourceRemote)
+	}
+	for _, cidr := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+			return fmt.Errorf("%q is not a valid CIDR or IP address", cidr)
+		}
+	}
+	return nil
+}
+
+// Policies returns the Contour allow and deny policies of the filter.
+func (f *IPFilter) Policies() (allow, deny []v1.IPFilterPolicy) {
+	source := f.Source
+	if source == "" {
+		source = v1.IPFilterSourceRemote
+	}
+	for _, cidr := range f.Allow {
+		allow = append(allow, v1.IPFilterPolicy{Source: source, CIDR: cidr})
+	}
+	for _, cidr := range f.Deny {
+		deny = append(deny, v1.IPFilterPolicy{Source: source, CIDR: cidr})
+	}
+	return allow, deny
+}
+
+// DownstreamTLS holds the TLS settings of the connections from clients to Envoy.
+type DownstreamTLS struct {
+	// MinimumProtocolVersion and MaximumProtocolVersion bound the negotiated
+	// TLS version, 1.2 or 1.3.
+	MinimumProtocolVersion string `json:"minimumProtocolVersion,omitempty"`
+	MaximumProtocolVersion string `json:"maximumProtocolVersion,omitempty"`
+	// ClientValidation, when set, requests client certificates.
+	ClientValidation *v1.DownstreamValidation `json:"clientValidation,omitempty"`
+}
+
+// Validate checks the protocol versions and client validation of the settings.
+func (t *DownstreamTLS) Validate() error {
+	for field, version := range map[string]string{
+		"minimumProtocolVersion": t.MinimumProtocolVersion,
+		"maximumProtocolVersion": t.MaximumProtocolVersion,
+	} {
+		switch version {
+		case "", "1.2", "1.3":
+		default:
+			return fmt.Errorf("%s %q is invalid, must be 1.2 or 1.3", field, version)
+		}
+	}
+	if t.MinimumProtocolVersion == "1.3" && t.MaximumProtocolVersion == "1.2" {
+		return errors.New("minimumProtocolVersion may not exceed maximumProtocolVersion")
+	}
+
+	cv := t.ClientValidation
+	if cv == nil {
+		return nil
+	}
+	if cv.CACertificate == "" && !cv.SkipClientCertValidation {
+		return errors.New("clientValidation.caSecret is required unless skipClientCertValidation is set")
+	}
+	if cv.CertificateRevocationList != "" && cv.SkipClientCertValidation {
+		return errors.New("clientValidation.crlSecret may not be set with skipClientCertValidation")
+	}
+	if cv.OnlyVerifyLeafCertCrl && cv.CertificateRevocationList == "" {
+		return errors.New("clientValidation.crlOnlyVerifyLeafCert requires crlSecret")
+	}
+	return nil
+}
+
+// RequiresClientCertificate returns whether Envoy rejects the TLS handshakes
+// of clients without a certificate.
+func (t *DownstreamTLS) RequiresClientCertificate() bool {
+	cv := t.ClientValidation
+	return cv != nil && !cv.OptionalClientCertificate && cv.CACertificate != ""
+}
+
+// AddressSource names where the addresses of an Envoy service come from.
+type AddressSource string
+
+const (
+	// AddressSourceClusterIP reports the Envoy service's ClusterIP.
+	AddressSourceClusterIP AddressSource = "ClusterIP"
+	// AddressSourceLoadBalancer reports the IPs and hostnames from the Envoy
+	// service's LoadBalancer status.
+	AddressSourceLoadBalancer AddressSource = "LoadBalancer"
+	// AddressSourceExternalIPs reports the Envoy service's externalIPs.
+	AddressSourceExternalIPs AddressSource = "ExternalIPs"
+	// AddressSourceStatic reports a fixed IP and/or domain.
+	AddressSourceStatic AddressSource = "Static"
+)
+
+// LoadBalancerAddress configures the load balancer addresses reported for
+// a visibility.
+type LoadBalancerAddress struct {
+	Source AddressSource `json:"source"`
+	// IP and Domain are only used with AddressSourceStatic.
+	IP     string `json:"ip,omitempty"`
+	Domain string `json:"domain,omitempty"`
+}
+
+// ProbePorts selects the ports of an Envoy service that are probed, each by
+// its number or its name.
+type ProbePorts struct {
+	HTTP  intstr.IntOrString `json:"http"`
+	HTTPS intstr.IntOrString `json:"https"`
+}
+
+// defaultProbePorts are the ports that Envoy listens on by default.
+var defaultProbePorts = ProbePorts{
+	HTTP:  intstr.FromInt(80),
+	HTTPS: intstr.FromInt(443),
+}
+
+// ProbePortsFor returns the ports of the Envoy services of the given visibility
+// that are probed.
+func (c *Contour) ProbePortsFor(vis v1alpha1.IngressVisibility) ProbePorts {
+	if ports, ok := c.VisibilityProbePorts[vis]; ok {
+		return ports
+	}
+	return defaultProbePorts
+}
+
+// DefaultTLSSecretFor returns the default TLS secret of the given external host:
+// the most specific of DefaultTLSSecrets that matches it, or else DefaultTLSSecret.
+func (c *Contour) DefaultTLSSecretFor(host string) *types.NamespacedName {
+	var secret *types.NamespacedName
+	matched := 0
+	for domain, s := range c.DefaultTLSSecrets {
+		// The length of the matched suffix ranks the matches, so that an exact
+		// domain beats the wildcard of its parent.
+		length := 0
+		if suffix, ok := strings.CutPrefix(domain, "*"); ok {
+			// Like the wildcard certificates, the wildcard only covers one label.
+			if label, ok := strings.CutSuffix(host, suffix); ok && label != "" && !strings.Contains(label, ".") {
+				length = len(suffix)
+			}
+		} else if host == domain {
+			length = len(domain)
+		}
+		if length > matched {
+			s := s
+			secret, matched = &s, length
+		}
+	}
+	if secret != nil {
+		return secret
+	}
+	return c.DefaultTLSSecret
+}
+
+// BackendNamespaceAllowed returns whether a KIngress in ingressNamespace may
+// route to Services in backendNamespace.
+func (c *Contour) BackendNamespaceAllowed(ingressNamespace, backendNamespace string) bool {
+	return backendNamespace == ingressNamespace ||
+		c.AllowedBackendNamespaces.Has("*") ||
+		c.AllowedBackendNamespaces.Has(backendNamespace)
 }
 
 type visibilityValue struct {
 	Class   string `json:"class"`
 	Service string `json:"service"`
+	// Services lists additional Envoy services that share the class,
+	// e.g. one fleet per zone.  Each of them is probed separately.
+	Services []string `json:"services"`
+	// Address configures the addresses reported in the KIngress status.
+	Address *LoadBalancerAddress `json:"address"`
+	// Ports configures the ports of the Envoy services that are probed.
+	Ports *ProbePorts `json:"ports"`
 }
 
 // NewContourFromConfigMap creates a Contour config from the supplied ConfigMap
@@ -66,15 +346,35 @@ func NewContourFromConfigMap(configMap *corev1.ConfigMap) (*Contour, error) {
 	timeoutPolicyResponse := "infinity"
 	timeoutPolicyIdle := "infinity"
 	var contourCORSPolicy *v1.CORSPolicy
+	backendNamespaces := sets.New[string]()
+	var rootProxyNamespace string
 
 	if err := configmap.Parse(configMap.Data,
 		configmap.AsOptionalNamespacedName(defaultTLSSecretConfigKey, &tlsSecret),
 		asContourDuration(timeoutPolicyResponseKey, &timeoutPolicyResponse),
 		asContourDuration(timeoutPolicyIdleKey, &timeoutPolicyIdle),
+		configmap.AsStringSet(allowedBackendNamespaces, &backendNamespaces),
+		configmap.AsString(rootProxyNamespaceKey, &rootProxyNamespace),
 	); err != nil {
 		return nil, err
 	}
 
+	backendNamespaces.Delete("")
+	for _, ns := range sets.List(backendNamespaces) {
+		if ns == "*" {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) != 0 {
+			return nil, fmt.Errorf("%s contains invalid namespace %q: %s", allowedBackendNamespaces, ns, strings.Join(errs, ", "))
+		}
+	}
+
+	if rootProxyNamespace != "" {
+		if errs := validation.IsDNS1123Label(rootProxyNamespace); len(errs) != 0 {
+			return nil, fmt.Errorf("%s %q is invalid: %s", rootProxyNamespaceKey, rootProxyNamespace, strings.Join(errs, ", "))
+		}
+	}
+
 	cors, ok := configMap.Data[corsPolicy]
 	if ok {
 		if err := yaml.Unmarshal([]byte(cors), &contourCORSPolicy); err != nil {
@@ -110,46 +410,158 @@ func NewContourFromConfigMap(configMap *corev1.ConfigMap) (*Contour, error) {
 		}
 	}
 
+	var localRateLimit *v1.LocalRateLimitPolicy
+	if raw, ok := configMap.Data[localRateLimitKey]; ok {
+		var err error
+		if localRateLimit, err = ParseLocalRateLimit(raw); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", localRateLimitKey, err)
+		}
+	}
+	localRateLimitVisibilities := sets.New(v1alpha1.IngressVisibilityExternalIP)
+	if raw, ok := configMap.Data[localRateLimitVisKey]; ok {
+		localRateLimitVisibilities = sets.New[v1alpha1.IngressVisibility]()
+		for _, vis := range strings.Split(raw, ",") {
+			switch vis := v1alpha1.IngressVisibility(strings.TrimSpace(vis)); vis {
+			case "":
+			case v1alpha1.IngressVisibilityExternalIP, v1alpha1.IngressVisibilityClusterLocal:
+				localRateLimitVisibilities.Insert(vis)
+			default:
+				return nil, fmt.Errorf("%s contains unrecognized visibility %q", localRateLimitVisKey, vis)
+			}
+		}
+	}
+
+	var globalRateLimit []v1.RateLimitDescriptor
+	if raw, ok := configMap.Data[globalRateLimitKey]; ok {
+		var err error
+		if globalRateLimit, err = ParseRateLimitDescriptors(raw); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", globalRateLimitKey, err)
+		}
+	}
+
+	ipFilters := make(map[v1alpha1.IngressVisibility]IPFilter, 2)
+	if raw, ok := configMap.Data[ipFilterPolicyKey]; ok {
+		if err := yaml.UnmarshalStrict([]byte(raw), &ipFilters); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", ipFilterPolicyKey, err)
+		}
+		for vis, filter := range ipFilters {
+			switch vis {
+			case v1alpha1.IngressVisibilityClusterLocal, v1alpha1.IngressVisibilityExternalIP:
+			default:
+				return nil, fmt.Errorf("%s contains unrecognized visibility %q", ipFilterPolicyKey, vis)
+			}
+			if err := filter.Validate(); err != nil {
+				return nil, fmt.Errorf("%s for %s is invalid: %w", ipFilterPolicyKey, vis, err)
+			}
+		}
+	}
+
+	var jwtProviders map[string]v1.JWTProvider
+	if raw, ok := configMap.Data[jwtProvidersKey]; ok {
+		var err error
+		if jwtProviders, err = parseJWTProviders(raw); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", jwtProvidersKey, err)
+		}
+	}
+
+	ingressClassMode := IngressClassModeAnnotation
+	if raw, ok := configMap.Data[ingressClassModeKey]; ok {
+		switch mode := IngressClassMode(strings.TrimSpace(raw)); mode {
+		case IngressClassModeAnnotation, IngressClassModeField, IngressClassModeBoth:
+			ingressClassMode = mode
+		default:
+			return nil, fmt.Errorf("%s %q is invalid, must be one of %s, %s or %s", ingressClassModeKey, raw,
+				IngressClassModeAnnotation, IngressClassModeField, IngressClassModeBoth)
+		}
+	}
+
+	var defaultTLSSecrets map[string]types.NamespacedName
+	if raw, ok := configMap.Data[defaultTLSSecretsKey]; ok {
+		var err error
+		if defaultTLSSecrets, err = parseDefaultTLSSecrets(raw); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", defaultTLSSecretsKey, err)
+		}
+	}
+
+	var defaultAuthorization *v1.AuthorizationServer
+	if raw, ok := configMap.Data[defaultAuthorizationKey]; ok {
+		var err error
+		if defaultAuthorization, err = parseDefaultAuthorization(raw); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", defaultAuthorizationKey, err)
+		}
+	}
+
+	var downstreamTLS *DownstreamTLS
+	if raw, ok := configMap.Data[downstreamTLSKey]; ok {
+		if err := yaml.UnmarshalStrict([]byte(raw), &downstreamTLS); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", downstreamTLSKey, err)
+		}
+		if downstreamTLS == nil {
+			return nil, fmt.Errorf("%s is empty", downstreamTLSKey)
+		}
+		if err := downstreamTLS.Validate(); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", downstreamTLSKey, err)
+		}
+	}
+
+	var retryPolicy *v1.RetryPolicy
+	if raw, ok := configMap.Data[retryPolicyConfigKey]; ok {
+		var err error
+		if retryPolicy, err = ParseRetryPolicy(raw); err != nil {
+			return nil, fmt.Errorf("%s is invalid: %w", retryPolicyConfigKey, err)
+		}
+	}
+
+	contour := &Contour{
+		DefaultTLSSecret:         tlsSecret,
+		DefaultTLSSecrets:        defaultTLSSecrets,
+		TimeoutPolicyResponse:    timeoutPolicyResponse,
+		TimeoutPolicyIdle:        timeoutPolicyIdle,
+		CORSPolicy:               contourCORSPolicy,
+		AllowedBackendNamespaces: backendNamespaces,
+		VisibilityAddresses:      make(map[v1alpha1.IngressVisibility]LoadBalancerAddress, 2),
+		VisibilityProbePorts:     make(map[v1alpha1.IngressVisibility]ProbePorts, 2),
+		RetryPolicy:              retryPolicy,
+
+		LocalRateLimit:             localRateLimit,
+		LocalRateLimitVisibilities: localRateLimitVisibilities,
+		GlobalRateLimitDescriptors: globalRateLimit,
+		IPFilters:                  ipFilters,
+		JWTProviders:               jwtProviders,
+		DefaultAuthorization:       defaultAuthorization,
+		DownstreamTLS:              downstreamTLS,
+		IngressClassMode:           ingressClassMode,
+		RootProxyNamespace:         rootProxyNamespace,
+	}
+
 	v, ok := configMap.Data[visibilityConfigKey]
 	if !ok {
 		// These are the defaults.
-		return &Contour{
-			DefaultTLSSecret: tlsSecret,
-			VisibilityKeys: map[v1alpha1.IngressVisibility]sets.Set[string]{
-				v1alpha1.IngressVisibilityClusterLocal: sets.New("contour-internal/envoy"),
-				v1alpha1.IngressVisibilityExternalIP:   sets.New("contour-external/envoy"),
-			},
-			VisibilityClasses: map[v1alpha1.IngressVisibility]string{
-				v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
-				v1alpha1.IngressVisibilityExternalIP:   "contour-external",
-			},
-			TimeoutPolicyResponse: timeoutPolicyResponse,
-			TimeoutPolicyIdle:     timeoutPolicyIdle,
-			CORSPolicy:            contourCORSPolicy,
-		}, nil
+		contour.VisibilityKeys = map[v1alpha1.IngressVisibility]sets.Set[string]{
+			v1alpha1.IngressVisibilityClusterLocal: sets.New("contour-internal/envoy"),
+			v1alpha1.IngressVisibilityExternalIP:   sets.New("contour-external/envoy"),
+		}
+		contour.VisibilityClasses = map[v1alpha1.IngressVisibility]string{
+			v1alpha1.IngressVisibilityClusterLocal: "contour-internal",
+			v1alpha1.IngressVisibilityExternalIP:   "contour-external",
+		}
+		return contour, nil
 	}
 	entry := make(map[v1alpha1.IngressVisibility]visibilityValue)
 	if err := yaml.Unmarshal([]byte(v), &entry); err != nil {
 		return nil, err
 	}
-
 	for _, vis := range []v1alpha1.IngressVisibility{
 		v1alpha1.IngressVisibilityClusterLocal,
 		v1alpha1.IngressVisibilityExternalIP,
 	} {
 		if _, ok := entry[vis]; !ok {
-			return nil, fmt.Errorf("visibility must contain %q with class and service", vis)
+			return nil, fmt.Errorf("visibility must contain %q with class and service or services", vis)
 		}
 	}
 
-	contour := &Contour{
-		DefaultTLSSecret:      tlsSecret,
-		VisibilityKeys:        make(map[v1alpha1.IngressVisibility]sets.Set[string], 2),
-		VisibilityClasses:     make(map[v1alpha1.IngressVisibility]string, 2),
-		TimeoutPolicyResponse: timeoutPolicyResponse,
-		TimeoutPolicyIdle:     timeoutPolicyIdle,
-		CORSPolicy:            contourCORSPolicy,
-	}
+	contour.VisibilityKeys = make(map[v1alpha1.IngressVisibility]sets.Set[string], 2)
+	contour.VisibilityClasses = make(map[v1alpha1.IngressVisibility]string, 2)
 	for key, value := range entry {
 		// Check that the visibility makes sense.
 		switch key {
@@ -158,29 +570,325 @@ func NewContourFromConfigMap(configMap *corev1.ConfigMap) (*Contour, error) {
 			return nil, fmt.Errorf("unrecognized visibility: %q", key)
 		}
 
-		// See if the Service is a valid namespace/name token.
-		if _, _, err := cache.SplitMetaNamespaceKey(value.Service); err != nil {
-			return nil, err
+		services := sets.New(value.Services...)
+		if value.Service != "" {
+			services.Insert(value.Service)
+		}
+		if services.Len() == 0 {
+			return nil, fmt.Errorf("visibility %q must contain at least one service", key)
+		}
+		for _, service := range sets.List(services) {
+			// See if the Service is a valid namespace/name token.
+			if _, _, err := cache.SplitMetaNamespaceKey(service); err != nil {
+				return nil, err
+			}
 		}
-		contour.VisibilityKeys[key] = sets.New(value.Service)
+		contour.VisibilityKeys[key] = services
 		contour.VisibilityClasses[key] = value.Class
+
+		if value.Address != nil {
+			if err := value.Address.validate(); err != nil {
+				return nil, fmt.Errorf("visibility %q has an invalid address: %w", key, err)
+			}
+			contour.VisibilityAddresses[key] = *value.Address
+		}
+
+		if value.Ports != nil {
+			if err := value.Ports.validate(); err != nil {
+				return nil, fmt.Errorf("visibility %q has invalid ports: %w", key, err)
+			}
+			contour.VisibilityProbePorts[key] = *value.Ports
+		}
 	}
 	return contour, nil
 }
 
+// ParseRetryPolicy parses and validates the YAML representation of a
+// Contour retry policy.
+func ParseRetryPolicy(raw string) (*v1.RetryPolicy, error) {
+	var policy *v1.RetryPolicy
+	if err := yaml.UnmarshalStrict([]byte(raw), &policy); err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, errors.New("the retry policy is empty")
+	}
+
+	if policy.NumRetries < -1 {
+		return nil, fmt.Errorf("count must be -1 (to disable retries) or greater, got %d", policy.NumRetries)
+	}
+	if policy.PerTryTimeout != "" {
+		if err := ValidateDuration(policy.PerTryTimeout); err != nil {
+			return nil, fmt.Errorf("perTryTimeout %q is invalid: %w", policy.PerTryTimeout, err)
+		}
+	}
+	for _, on := range policy.RetryOn {
+		if !validRetryOn.Has(on) {
+			return nil, fmt.Errorf("retryOn %q is not a condition supported by Contour", on)
+		}
+	}
+	for _, code := range policy.RetriableStatusCodes {
+		if code < 100 || code > 599 {
+			return nil, fmt.Errorf("retriableStatusCodes contains invalid HTTP status code %d", code)
+		}
+	}
+	if len(policy.RetriableStatusCodes) != 0 && !sets.New(policy.RetryOn...).Has("retriable-status-codes") {
+		return nil, errors.New("retriableStatusCodes requires retryOn to contain retriable-status-codes")
+	}
+	return policy, nil
+}
+
+// ParseLocalRateLimit parses and validates the YAML representation of a
+// Contour local rate limit policy.
+func ParseLocalRateLimit(raw string) (*v1.LocalRateLimitPolicy, error) {
+	var policy *v1.LocalRateLimitPolicy
+	if err := yaml.UnmarshalStrict([]byte(raw), &policy); err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, errors.New("the local rate limit is empty")
+	}
+
+	if policy.Requests == 0 {
+		return nil, errors.New("requests must be at least 1")
+	}
+	switch policy.Unit {
+	case "second", "minute", "hour":
+	default:
+		return nil, fmt.Errorf("unit %q is invalid, must be one of second, minute or hour", policy.Unit)
+	}
+	if code := policy.ResponseStatusCode; code != 0 && (code < 400 || code > 599) {
+		return nil, fmt.Errorf("responseStatusCode must be within 400-599, got %d", code)
+	}
+	return policy, nil
+}
+
+// ParseRateLimitDescriptors parses and validates the YAML representation of
+// a list of Contour global rate limit descriptors.
+func ParseRateLimitDescriptors(raw string) ([]v1.RateLimitDescriptor, error) {
+	var descriptors []v1.RateLimitDescriptor
+	if err := yaml.UnmarshalStrict([]byte(raw), &descriptors); err != nil {
+		return nil, err
+	}
+	if len(descriptors) == 0 {
+		return nil, errors.New("at least one descriptor is required")
+	}
+
+	for i, descriptor := range descriptors {
+		if len(descriptor.Entries) == 0 {
+			return nil, fmt.Errorf("descriptor %d has no entries", i)
+		}
+		for j, entry := range descriptor.Entries {
+			if err := validateDescriptorEntry(entry); err != nil {
+				return nil, fmt.Errorf("descriptor %d entry %d is invalid: %w", i, j, err)
+			}
+		}
+	}
+	return descriptors, nil
+}
+
+func validateDescriptorEntry(entry v1.RateLimitDescriptorEntry) error {
+	set := 0
+	if entry.GenericKey != nil {
+		set++
+		if entry.GenericKey.Value == "" {
+			return errors.New("genericKey.value is required")
+		}
+	}
+	if entry.RequestHeader != nil {
+		set++
+		if entry.RequestHeader.HeaderName == "" || entry.RequestHeader.DescriptorKey == "" {
+			return errors.New("requestHeader.headerName and requestHeader.descriptorKey are required")
+		}
+	}
+	if entry.RequestHeaderValueMatch != nil {
+		set++
+		if len(entry.RequestHeaderValueMatch.Headers) == 0 || entry.RequestHeaderValueMatch.Value == "" {
+			return errors.New("requestHeaderValueMatch.headers and requestHeaderValueMatch.value are required")
+		}
+		for _, header := range entry.RequestHeaderValueMatch.Headers {
+			if header.Name == "" {
+				return errors.New("requestHeaderValueMatch.headers must all have a name")
+			}
+		}
+	}
+	if entry.RemoteAddress != nil {
+		set++
+	}
+	if set != 1 {
+		return errors.New("exactly one of genericKey, requestHeader, requestHeaderValueMatch or remoteAddress must be set")
+	}
+	return nil
+}
+
+func parseJWTProviders(raw string) (map[string]v1.JWTProvider, error) {
+	var providers map[string]v1.JWTProvider
+	if err := yaml.UnmarshalStrict([]byte(raw), &providers); err != nil {
+		return nil, err
+	}
+
+	for name, provider := range providers {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+			return nil, fmt.Errorf("provider name %q is invalid: %s", name, strings.Join(errs, ", "))
+		}
+		if provider.Name != "" && provider.Name != name {
+			return nil, fmt.Errorf("provider %q has a different name %q", name, provider.Name)
+		}
+		if provider.Default {
+			return nil, fmt.Errorf("provider %q may not set default, it is selected per KIngress", name)
+		}
+		jwks := provider.RemoteJWKS
+		if u, err := url.Parse(jwks.URI); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("provider %q has an invalid remoteJWKS.uri %q, must be an http or https URL", name, jwks.URI)
+		}
+		for field, value := range map[string]string{
+			"remoteJWKS.timeout":       jwks.Timeout,
+			"remoteJWKS.cacheDuration": jwks.CacheDuration,
+		} {
+			if value == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(value); err != nil {
+				return nil, fmt.Errorf("provider %q has an invalid %s: %w", name, field, err)
+			}
+		}
+		switch jwks.DNSLookupFamily {
+		case "", "auto", "v4", "v6", "all":
+		default:
+			return nil, fmt.Errorf("provider %q has an invalid remoteJWKS.dnsLookupFamily %q, must be one of auto, v4, v6 or all", name, jwks.DNSLookupFamily)
+		}
+		if v := jwks.UpstreamValidation; v != nil && (v.CACertificate == "" || (v.SubjectName == "" && len(v.SubjectNames) == 0)) {
+			return nil, fmt.Errorf("provider %q requires remoteJWKS.validation.caSecret and a subject name", name)
+		}
+
+		provider.Name = name
+		providers[name] = provider
+	}
+	return providers, nil
+}
+
+func parseDefaultTLSSecrets(raw string) (map[string]types.NamespacedName, error) {
+	var entries map[string]string
+	if err := yaml.UnmarshalStrict([]byte(raw), &entries); err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]types.NamespacedName, len(entries))
+	for domain, secret := range entries {
+		if errs := validation.IsDNS1123Subdomain(strings.TrimPrefix(domain, "*.")); len(errs) != 0 {
+			return nil, fmt.Errorf("domain %q is invalid, must be a domain or *. followed by one: %s", domain, strings.Join(errs, ", "))
+		}
+		namespace, name, err := cache.SplitMetaNamespaceKey(secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret %q of domain %q is invalid: %w", secret, domain, err)
+		}
+		if namespace == "" || name == "" {
+			return nil, fmt.Errorf("secret %q of domain %q must be given as namespace/name", secret, domain)
+		}
+		secrets[domain] = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	return secrets, nil
+}
+
+func parseDefaultAuthorization(raw string) (*v1.AuthorizationServer, error) {
+	var auth *v1.AuthorizationServer
+	if err := yaml.UnmarshalStrict([]byte(raw), &auth); err != nil {
+		return nil, err
+	}
+	if auth == nil {
+		return nil, errors.New("the default authorization is empty")
+	}
+
+	// The ExtensionService is referenced from HTTPProxies in every namespace.
+	if auth.ExtensionServiceRef.Name == "" || auth.ExtensionServiceRef.Namespace == "" {
+		return nil, errors.New("extensionRef.name and extensionRef.namespace are required")
+	}
+	if auth.ResponseTimeout != "" {
+		if err := ValidateDuration(auth.ResponseTimeout); err != nil {
+			return nil, fmt.Errorf("responseTimeout is invalid: %w", err)
+		}
+	}
+	if auth.AuthPolicy != nil && auth.AuthPolicy.Disabled {
+		return nil, errors.New("authPolicy.disabled may not be set, KIngresses opt out with an annotation")
+	}
+	return auth, nil
+}
+
+func (a *LoadBalancerAddress) validate() error {
+	switch a.Source {
+	case "":
+		a.Source = AddressSourceClusterIP
+	case AddressSourceClusterIP, AddressSourceLoadBalancer, AddressSourceExternalIPs:
+	case AddressSourceStatic:
+		if a.IP == "" && a.Domain == "" {
+			return errors.New("source Static requires ip or domain")
+		}
+		if a.IP != "" && net.ParseIP(a.IP) == nil {
+			return fmt.Errorf("ip %q is not a valid IP address", a.IP)
+		}
+		if a.Domain != "" {
+			if errs := validation.IsDNS1123Subdomain(a.Domain); len(errs) != 0 {
+				return fmt.Errorf("domain %q is invalid: %s", a.Domain, strings.Join(errs, ", "))
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown source %q, must be one of %s, %s, %s or %s", a.Source,
+			AddressSourceClusterIP, AddressSourceLoadBalancer, AddressSourceExternalIPs, AddressSourceStatic)
+	}
+	if a.IP != "" || a.Domain != "" {
+		return fmt.Errorf("ip and domain may only be set with source %s", AddressSourceStatic)
+	}
+	return nil
+}
+
+// validate checks the ports, and defaults those that are not set.
+func (p *ProbePorts) validate() error {
+	for _, port := range []struct {
+		name  string
+		value *intstr.IntOrString
+		def   intstr.IntOrString
+	}{
+		{"http", &p.HTTP, defaultProbePorts.HTTP},
+		{"https", &p.HTTPS, defaultProbePorts.HTTPS},
+	} {
+		var errs []string
+		switch {
+		case *port.value == intstr.IntOrString{}:
+			*port.value = port.def
+		case port.value.Type == intstr.String:
+			errs = validation.IsValidPortName(port.value.StrVal)
+		default:
+			errs = validation.IsValidPortNum(port.value.IntValue())
+		}
+		if len(errs) != 0 {
+			return fmt.Errorf("%s port %q is invalid: %s", port.name, port.value.String(), strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
 func asContourDuration(key string, target *string) configmap.ParseFunc {
 	return func(data map[string]string) error {
 		if raw, ok := data[key]; ok {
-			if raw == "infinity" {
-				*target = raw
-			} else {
-				_, err := time.ParseDuration(raw)
-				if err != nil {
-					return fmt.Errorf("failed to parse %q: %w", key, err)
-				}
-				*target = raw
+			if err := ValidateDuration(raw); err != nil {
+				return fmt.Errorf("failed to parse %q: %w", key, err)
 			}
+			*target = raw
 		}
 		return nil
 	}
 }
+
+// contourDuration matches the durations Contour's CRDs accept for timeout and
+// retry policies.
+var contourDuration = regexp.MustCompile(`^(((\d*(\.\d*)?(h|m|s|ms|us|µs|ns))+)|infinity|infinite)$`)
+
+// ValidateDuration checks that raw is a duration Contour accepts for its
+// timeout and retry policies.
+func ValidateDuration(raw string) error {
+	if !contourDuration.MatchString(raw) {
+		return fmt.Errorf("invalid duration %q", raw)
+	}
+	return nil
+}
@@ -25,6 +25,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/system"
 
 	. "knative.dev/pkg/configmap/testing"
@@ -436,6 +439,794 @@ func TestTimeoutPolicyIdle(t *testing.T) {
 	}
 }
 
+func TestAllowedBackendNamespaces(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			allowedBackendNamespaces: "shared, platform",
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(allowed-backend-namespaces:shared,platform) =", err)
+	}
+
+	if got, want := cfg.AllowedBackendNamespaces, sets.New("shared", "platform"); !got.Equal(want) {
+		t.Errorf("AllowedBackendNamespaces got %v want %v", sets.List(got), sets.List(want))
+	}
+	for ns, want := range map[string]bool{
+		"ns":       true,
+		"shared":   true,
+		"platform": true,
+		"other":    false,
+	} {
+		if got := cfg.BackendNamespaceAllowed("ns", ns); got != want {
+			t.Errorf("BackendNamespaceAllowed(ns, %s) = %v, want %v", ns, got, want)
+		}
+	}
+
+	cm.Data[allowedBackendNamespaces] = "*"
+	cfg, err = NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(allowed-backend-namespaces:*) =", err)
+	}
+	if !cfg.BackendNamespaceAllowed("ns", "other") {
+		t.Error("BackendNamespaceAllowed(ns, other) = false, want true")
+	}
+
+	delete(cm.Data, allowedBackendNamespaces)
+	cfg, err = NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.BackendNamespaceAllowed("ns", "shared") {
+		t.Error("BackendNamespaceAllowed(ns, shared) = true, want false")
+	}
+
+	cm.Data[allowedBackendNamespaces] = "Not_A_Namespace"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Errorf("expected an error parsing erroneous %q", allowedBackendNamespaces)
+	}
+}
+
+func TestVisibilityServices(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			visibilityConfigKey: `
+ExternalIP:
+  class: contour-external
+  service: contour-external/envoy
+  services:
+  - contour-external/envoy-zone-a
+  - contour-external/envoy-zone-b
+ClusterLocal:
+  class: contour-internal
+  services:
+  - contour-internal/envoy`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(visibility) =", err)
+	}
+
+	want := map[v1alpha1.IngressVisibility]sets.Set[string]{
+		v1alpha1.IngressVisibilityExternalIP: sets.New(
+			"contour-external/envoy",
+			"contour-external/envoy-zone-a",
+			"contour-external/envoy-zone-b"),
+		v1alpha1.IngressVisibilityClusterLocal: sets.New("contour-internal/envoy"),
+	}
+	if !cmp.Equal(cfg.VisibilityKeys, want) {
+		t.Error("VisibilityKeys (-want, +got) =", cmp.Diff(want, cfg.VisibilityKeys))
+	}
+}
+
+func TestVisibilityAddresses(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			visibilityConfigKey: `
+ExternalIP:
+  class: contour-external
+  service: contour-external/envoy
+  address:
+    source: LoadBalancer
+ClusterLocal:
+  class: contour-internal
+  service: contour-internal/envoy`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(visibility) =", err)
+	}
+	want := map[v1alpha1.IngressVisibility]LoadBalancerAddress{
+		v1alpha1.IngressVisibilityExternalIP: {Source: AddressSourceLoadBalancer},
+	}
+	if !cmp.Equal(cfg.VisibilityAddresses, want) {
+		t.Error("VisibilityAddresses (-want, +got) =", cmp.Diff(want, cfg.VisibilityAddresses))
+	}
+
+	cm.Data[visibilityConfigKey] = `
+ExternalIP:
+  class: contour-external
+  service: contour-external/envoy
+  address:
+    source: Static
+    ip: 10.0.0.1
+    domain: ingress.example.com
+ClusterLocal:
+  class: contour-internal
+  service: contour-internal/envoy
+  address: {}`
+	cfg, err = NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(visibility) =", err)
+	}
+	want = map[v1alpha1.IngressVisibility]LoadBalancerAddress{
+		v1alpha1.IngressVisibilityExternalIP: {
+			Source: AddressSourceStatic,
+			IP:     "10.0.0.1",
+			Domain: "ingress.example.com",
+		},
+		v1alpha1.IngressVisibilityClusterLocal: {Source: AddressSourceClusterIP},
+	}
+	if !cmp.Equal(cfg.VisibilityAddresses, want) {
+		t.Error("VisibilityAddresses (-want, +got) =", cmp.Diff(want, cfg.VisibilityAddresses))
+	}
+
+	for name, address := range map[string]string{
+		"unknown source":     "source: NodePort",
+		"static without ip":  "source: Static",
+		"static bad ip":      "{source: Static, ip: not-an-ip}",
+		"static bad domain":  "{source: Static, domain: Not_A_Domain}",
+		"ip without static":  "{source: LoadBalancer, ip: 10.0.0.1}",
+		"domain without src": "{domain: ingress.example.com}",
+	} {
+		cm.Data[visibilityConfigKey] = `
+ExternalIP:
+  class: contour-external
+  service: contour-external/envoy
+  address: ` + address + `
+ClusterLocal:
+  class: contour-internal
+  service: contour-internal/envoy`
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing address %q", name, address)
+		}
+	}
+}
+
+func TestVisibilityProbePorts(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			visibilityConfigKey: `
+ExternalIP:
+  class: contour-external
+  service: contour-external/envoy
+  ports:
+    http: 8080
+    https: https
+ClusterLocal:
+  class: contour-internal
+  service: contour-internal/envoy
+  ports:
+    http: http`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(visibility) =", err)
+	}
+	want := map[v1alpha1.IngressVisibility]ProbePorts{
+		v1alpha1.IngressVisibilityExternalIP: {
+			HTTP:  intstr.FromInt(8080),
+			HTTPS: intstr.FromString("https"),
+		},
+		v1alpha1.IngressVisibilityClusterLocal: {
+			HTTP:  intstr.FromString("http"),
+			HTTPS: intstr.FromInt(443),
+		},
+	}
+	if !cmp.Equal(cfg.VisibilityProbePorts, want) {
+		t.Error("VisibilityProbePorts (-want, +got) =", cmp.Diff(want, cfg.VisibilityProbePorts))
+	}
+
+	// Visibilities without ports are probed on the default ones.
+	cfg, err = NewContourFromConfigMap(&corev1.ConfigMap{})
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	wantPorts := ProbePorts{HTTP: intstr.FromInt(80), HTTPS: intstr.FromInt(443)}
+	if got := cfg.ProbePortsFor(v1alpha1.IngressVisibilityExternalIP); !cmp.Equal(got, wantPorts) {
+		t.Error("ProbePortsFor (-want, +got) =", cmp.Diff(wantPorts, got))
+	}
+
+	for name, ports := range map[string]string{
+		"port out of range": "{http: 70000}",
+		"negative port":     "{https: -1}",
+		"invalid port name": "{http: Not_A_Port_Name}",
+	} {
+		cm.Data[visibilityConfigKey] = `
+ExternalIP:
+  class: contour-external
+  service: contour-external/envoy
+  ports: ` + ports + `
+ClusterLocal:
+  class: contour-internal
+  service: contour-internal/envoy`
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing ports %q", name, ports)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			retryPolicyConfigKey: `
+count: 3
+perTryTimeout: 500ms
+retryOn:
+  - retriable-status-codes
+  - connect-failure
+retriableStatusCodes:
+  - 503
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(retryPolicy) =", err)
+	}
+	want := &v1.RetryPolicy{
+		NumRetries:           3,
+		PerTryTimeout:        "500ms",
+		RetryOn:              []v1.RetryOn{"retriable-status-codes", "connect-failure"},
+		RetriableStatusCodes: []uint32{503},
+	}
+	if !cmp.Equal(cfg.RetryPolicy, want) {
+		t.Error("RetryPolicy (-want, +got) =", cmp.Diff(want, cfg.RetryPolicy))
+	}
+
+	delete(cm.Data, retryPolicyConfigKey)
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	} else if cfg.RetryPolicy != nil {
+		t.Errorf("RetryPolicy = %v, want nil", cfg.RetryPolicy)
+	}
+
+	for name, policy := range map[string]string{
+		"failure parsing yaml":  "moo",
+		"empty":                 "",
+		"unknown field":         "retries: 3",
+		"count too low":         "count: -2",
+		"invalid timeout":       "perTryTimeout: soon",
+		"negative timeout":      "perTryTimeout: -1s",
+		"unknown condition":     "retryOn: [sometimes]",
+		"invalid status code":   "{retryOn: [retriable-status-codes], retriableStatusCodes: [42]}",
+		"codes without retryOn": "{retryOn: [5xx], retriableStatusCodes: [503]}",
+	} {
+		cm.Data[retryPolicyConfigKey] = policy
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, retryPolicyConfigKey, policy)
+		}
+	}
+}
+
+func TestLocalRateLimit(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			localRateLimitKey: `
+requests: 100
+unit: minute
+burst: 10
+responseStatusCode: 503
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(localRateLimit) =", err)
+	}
+	want := &v1.LocalRateLimitPolicy{
+		Requests:           100,
+		Unit:               "minute",
+		Burst:              10,
+		ResponseStatusCode: 503,
+	}
+	if !cmp.Equal(cfg.LocalRateLimit, want) {
+		t.Error("LocalRateLimit (-want, +got) =", cmp.Diff(want, cfg.LocalRateLimit))
+	}
+	if got, want := cfg.LocalRateLimitVisibilities, sets.New(v1alpha1.IngressVisibilityExternalIP); !got.Equal(want) {
+		t.Errorf("LocalRateLimitVisibilities = %v, want %v", sets.List(got), sets.List(want))
+	}
+
+	cm.Data[localRateLimitVisKey] = "ExternalIP, ClusterLocal"
+	if cfg, err = NewContourFromConfigMap(cm); err != nil {
+		t.Fatal("NewContourFromConfigMap(localRateLimitVisibilities) =", err)
+	}
+	if got, want := cfg.LocalRateLimitVisibilities, sets.New(v1alpha1.IngressVisibilityExternalIP, v1alpha1.IngressVisibilityClusterLocal); !got.Equal(want) {
+		t.Errorf("LocalRateLimitVisibilities = %v, want %v", sets.List(got), sets.List(want))
+	}
+
+	cm.Data[localRateLimitVisKey] = "Everywhere"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Errorf("expected an error parsing %s %q", localRateLimitVisKey, cm.Data[localRateLimitVisKey])
+	}
+	delete(cm.Data, localRateLimitVisKey)
+
+	for name, policy := range map[string]string{
+		"failure parsing yaml":  "moo",
+		"empty":                 "",
+		"unknown field":         "{requests: 1, unit: second, rate: 3}",
+		"no requests":           "unit: second",
+		"invalid unit":          "{requests: 1, unit: day}",
+		"invalid response code": "{requests: 1, unit: second, responseStatusCode: 200}",
+	} {
+		cm.Data[localRateLimitKey] = policy
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, localRateLimitKey, policy)
+		}
+	}
+}
+
+func TestGlobalRateLimitDescriptors(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			globalRateLimitKey: `
+- entries:
+  - remoteAddress: {}
+- entries:
+  - genericKey:
+      key: service
+      value: knative
+  - requestHeader:
+      headerName: X-Tenant
+      descriptorKey: tenant
+  - requestHeaderValueMatch:
+      headers:
+      - name: X-Plan
+        exact: free
+      expectMatch: true
+      value: free-plan
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(globalRateLimitDescriptors) =", err)
+	}
+	want := []v1.RateLimitDescriptor{{
+		Entries: []v1.RateLimitDescriptorEntry{{
+			RemoteAddress: &v1.RemoteAddressDescriptor{},
+		}},
+	}, {
+		Entries: []v1.RateLimitDescriptorEntry{{
+			GenericKey: &v1.GenericKeyDescriptor{Key: "service", Value: "knative"},
+		}, {
+			RequestHeader: &v1.RequestHeaderDescriptor{HeaderName: "X-Tenant", DescriptorKey: "tenant"},
+		}, {
+			RequestHeaderValueMatch: &v1.RequestHeaderValueMatchDescriptor{
+				Headers:     []v1.HeaderMatchCondition{{Name: "X-Plan", Exact: "free"}},
+				ExpectMatch: true,
+				Value:       "free-plan",
+			},
+		}},
+	}}
+	if !cmp.Equal(cfg.GlobalRateLimitDescriptors, want) {
+		t.Error("GlobalRateLimitDescriptors (-want, +got) =", cmp.Diff(want, cfg.GlobalRateLimitDescriptors))
+	}
+
+	for name, descriptors := range map[string]string{
+		"failure parsing yaml":        "moo",
+		"empty":                       "[]",
+		"no entries":                  "- entries: []",
+		"unknown field":               "- entries: [{sourceIP: {}}]",
+		"two kinds in one entry":      "- entries: [{remoteAddress: {}, genericKey: {value: v}}]",
+		"generic key without value":   "- entries: [{genericKey: {key: k}}]",
+		"request header without key":  "- entries: [{requestHeader: {headerName: X-Foo}}]",
+		"value match without headers": "- entries: [{requestHeaderValueMatch: {value: v}}]",
+		"value match with no name":    "- entries: [{requestHeaderValueMatch: {value: v, headers: [{exact: x}]}}]",
+		"value match without a value": "- entries: [{requestHeaderValueMatch: {headers: [{name: X-Foo, present: true}]}}]",
+	} {
+		cm.Data[globalRateLimitKey] = descriptors
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, globalRateLimitKey, descriptors)
+		}
+	}
+}
+
+func TestIPFilterPolicy(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			ipFilterPolicyKey: `
+ExternalIP:
+  deny:
+  - 192.0.2.0/24
+  - 2001:db8::1
+ClusterLocal:
+  allow:
+  - 10.0.0.0/8
+  source: Peer
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(ipFilterPolicy) =", err)
+	}
+	want := map[v1alpha1.IngressVisibility]IPFilter{
+		v1alpha1.IngressVisibilityExternalIP: {
+			Deny: []string{"192.0.2.0/24", "2001:db8::1"},
+		},
+		v1alpha1.IngressVisibilityClusterLocal: {
+			Allow:  []string{"10.0.0.0/8"},
+			Source: v1.IPFilterSourcePeer,
+		},
+	}
+	if !cmp.Equal(cfg.IPFilters, want) {
+		t.Error("IPFilters (-want, +got) =", cmp.Diff(want, cfg.IPFilters))
+	}
+
+	for name, policy := range map[string]string{
+		"failure parsing yaml": "moo",
+		"unknown visibility":   "Everywhere: {allow: [10.0.0.0/8]}",
+		"unknown field":        "ExternalIP: {permit: [10.0.0.0/8]}",
+		"allow and deny":       "ExternalIP: {allow: [10.0.0.0/8], deny: [10.1.0.0/16]}",
+		"invalid cidr":         "ExternalIP: {allow: [10.0.0.0/33]}",
+		"invalid source":       "ExternalIP: {allow: [10.0.0.0/8], source: Header}",
+	} {
+		cm.Data[ipFilterPolicyKey] = policy
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, ipFilterPolicyKey, policy)
+		}
+	}
+}
+
+func TestJWTProviders(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			jwtProvidersKey: `
+corp:
+  issuer: https://auth.example.com
+  audiences: [knative]
+  forwardJWT: true
+  remoteJWKS:
+    uri: https://auth.example.com/jwks.json
+    timeout: 2s
+    cacheDuration: 10m
+    validation:
+      caSecret: auth/ca
+      subjectName: auth.example.com
+partner:
+  remoteJWKS:
+    uri: http://jwks.partner.svc.cluster.local/keys
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(jwtProviders) =", err)
+	}
+	want := map[string]v1.JWTProvider{
+		"corp": {
+			Name:       "corp",
+			Issuer:     "https://auth.example.com",
+			Audiences:  []string{"knative"},
+			ForwardJWT: true,
+			RemoteJWKS: v1.RemoteJWKS{
+				URI:           "https://auth.example.com/jwks.json",
+				Timeout:       "2s",
+				CacheDuration: "10m",
+				UpstreamValidation: &v1.UpstreamValidation{
+					CACertificate: "auth/ca",
+					SubjectName:   "auth.example.com",
+				},
+			},
+		},
+		"partner": {
+			Name: "partner",
+			RemoteJWKS: v1.RemoteJWKS{
+				URI: "http://jwks.partner.svc.cluster.local/keys",
+			},
+		},
+	}
+	if !cmp.Equal(cfg.JWTProviders, want) {
+		t.Error("JWTProviders (-want, +got) =", cmp.Diff(want, cfg.JWTProviders))
+	}
+
+	for name, providers := range map[string]string{
+		"failure parsing yaml":   "moo",
+		"unknown field":          "corp: {remoteJWKS: {uri: 'https://a.b/k'}, scopes: [x]}",
+		"invalid name":           "Corp_1: {remoteJWKS: {uri: 'https://a.b/k'}}",
+		"mismatched name":        "corp: {name: other, remoteJWKS: {uri: 'https://a.b/k'}}",
+		"default":                "corp: {default: true, remoteJWKS: {uri: 'https://a.b/k'}}",
+		"missing uri":            "corp: {issuer: me}",
+		"relative uri":           "corp: {remoteJWKS: {uri: /keys}}",
+		"invalid timeout":        "corp: {remoteJWKS: {uri: 'https://a.b/k', timeout: soon}}",
+		"invalid cache duration": "corp: {remoteJWKS: {uri: 'https://a.b/k', cacheDuration: infinity}}",
+		"invalid dns family":     "corp: {remoteJWKS: {uri: 'https://a.b/k', dnsLookupFamily: v5}}",
+		"incomplete validation":  "corp: {remoteJWKS: {uri: 'https://a.b/k', validation: {caSecret: ca}}}",
+	} {
+		cm.Data[jwtProvidersKey] = providers
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, jwtProvidersKey, providers)
+		}
+	}
+}
+
+func TestDefaultAuthorization(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			defaultAuthorizationKey: `
+extensionRef:
+  namespace: auth
+  name: authz
+responseTimeout: 1s
+failOpen: true
+authPolicy:
+  context:
+    cluster: knative
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(defaultAuthorization) =", err)
+	}
+	want := &v1.AuthorizationServer{
+		ExtensionServiceRef: v1.ExtensionServiceReference{
+			Namespace: "auth",
+			Name:      "authz",
+		},
+		ResponseTimeout: "1s",
+		FailOpen:        true,
+		AuthPolicy: &v1.AuthorizationPolicy{
+			Context: map[string]string{"cluster": "knative"},
+		},
+	}
+	if !cmp.Equal(cfg.DefaultAuthorization, want) {
+		t.Error("DefaultAuthorization (-want, +got) =", cmp.Diff(want, cfg.DefaultAuthorization))
+	}
+
+	for name, auth := range map[string]string{
+		"failure parsing yaml":     "moo",
+		"empty":                    "",
+		"unknown field":            "extensionRef: {namespace: auth, name: authz}\nscopes: [x]",
+		"missing name":             "extensionRef: {namespace: auth}",
+		"missing namespace":        "extensionRef: {name: authz}",
+		"invalid response timeout": "extensionRef: {namespace: auth, name: authz}\nresponseTimeout: soon",
+		"disabled":                 "extensionRef: {namespace: auth, name: authz}\nauthPolicy: {disabled: true}",
+	} {
+		cm.Data[defaultAuthorizationKey] = auth
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, defaultAuthorizationKey, auth)
+		}
+	}
+}
+
+func TestDownstreamTLS(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			downstreamTLSKey: `
+minimumProtocolVersion: "1.3"
+clientValidation:
+  caSecret: partners/ca
+  crlSecret: partners/crl
+  crlOnlyVerifyLeafCert: true
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(downstreamTLS) =", err)
+	}
+	want := &DownstreamTLS{
+		MinimumProtocolVersion: "1.3",
+		ClientValidation: &v1.DownstreamValidation{
+			CACertificate:             "partners/ca",
+			CertificateRevocationList: "partners/crl",
+			OnlyVerifyLeafCertCrl:     true,
+		},
+	}
+	if !cmp.Equal(cfg.DownstreamTLS, want) {
+		t.Error("DownstreamTLS (-want, +got) =", cmp.Diff(want, cfg.DownstreamTLS))
+	}
+	if !cfg.DownstreamTLS.RequiresClientCertificate() {
+		t.Error("RequiresClientCertificate() = false, wanted true")
+	}
+
+	for name, tls := range map[string]string{
+		"failure parsing yaml":    "moo",
+		"empty":                   "",
+		"unknown field":           "minimumProtocolVersion: '1.2'\ncipherSuites: [x]",
+		"invalid minimum version": "minimumProtocolVersion: '1.1'",
+		"invalid maximum version": "maximumProtocolVersion: '1.4'",
+		"inverted versions":       "minimumProtocolVersion: '1.3'\nmaximumProtocolVersion: '1.2'",
+		"missing ca":              "clientValidation: {crlSecret: partners/crl}",
+		"crl without validation":  "clientValidation: {skipClientCertValidation: true, crlSecret: partners/crl}",
+		"leaf only without crl":   "clientValidation: {caSecret: partners/ca, crlOnlyVerifyLeafCert: true}",
+	} {
+		cm.Data[downstreamTLSKey] = tls
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, downstreamTLSKey, tls)
+		}
+	}
+}
+
+func TestDefaultTLSSecrets(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			defaultTLSSecretConfigKey: "certs/fallback",
+			defaultTLSSecretsKey: `
+"*.example.com": certs/wildcard
+"*.apps.example.com": certs/apps
+special.apps.example.com: certs/special
+`,
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(defaultTLSSecrets) =", err)
+	}
+
+	for host, want := range map[string]string{
+		"example.com":                "certs/fallback",
+		"a.example.com":              "certs/wildcard",
+		"a.b.example.com":            "certs/fallback",
+		"apps.example.com":           "certs/wildcard",
+		"a.apps.example.com":         "certs/apps",
+		"special.apps.example.com":   "certs/special",
+		"a.special.apps.example.com": "certs/fallback",
+		"notexample.com":             "certs/fallback",
+	} {
+		if got := cfg.DefaultTLSSecretFor(host); got == nil || got.String() != want {
+			t.Errorf("DefaultTLSSecretFor(%q) = %v, wanted %s", host, got, want)
+		}
+	}
+
+	delete(cm.Data, defaultTLSSecretConfigKey)
+	cfg, err = NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap(defaultTLSSecrets) =", err)
+	}
+	if got := cfg.DefaultTLSSecretFor("other.com"); got != nil {
+		t.Errorf("DefaultTLSSecretFor(other.com) = %v, wanted nil", got)
+	}
+
+	for name, secrets := range map[string]string{
+		"failure parsing yaml": "moo",
+		"invalid domain":       "'*.Example_com': certs/wildcard",
+		"inner wildcard":       "'a.*.example.com': certs/wildcard",
+		"missing namespace":    "'*.example.com': wildcard",
+		"invalid secret":       "'*.example.com': a/b/c",
+	} {
+		cm.Data[defaultTLSSecretsKey] = secrets
+		if _, err := NewContourFromConfigMap(cm); err == nil {
+			t.Errorf("%s: expected an error parsing %s %q", name, defaultTLSSecretsKey, secrets)
+		}
+	}
+}
+
+func TestIngressClassMode(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if cfg.IngressClassMode != IngressClassModeAnnotation {
+		t.Errorf("IngressClassMode = %q, wanted %q", cfg.IngressClassMode, IngressClassModeAnnotation)
+	}
+
+	for _, mode := range []IngressClassMode{IngressClassModeAnnotation, IngressClassModeField, IngressClassModeBoth} {
+		cm.Data[ingressClassModeKey] = string(mode)
+		cfg, err := NewContourFromConfigMap(cm)
+		if err != nil {
+			t.Fatalf("NewContourFromConfigMap(%s) = %v", mode, err)
+		}
+		if cfg.IngressClassMode != mode {
+			t.Errorf("IngressClassMode = %q, wanted %q", cfg.IngressClassMode, mode)
+		}
+	}
+
+	cm.Data[ingressClassModeKey] = "label"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Errorf("expected an error parsing %s %q", ingressClassModeKey, cm.Data[ingressClassModeKey])
+	}
+}
+
+func TestRootProxyNamespace(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      ContourConfigName,
+		},
+		Data: map[string]string{
+			rootProxyNamespaceKey: "contour-roots",
+		},
+	}
+
+	cfg, err := NewContourFromConfigMap(cm)
+	if err != nil {
+		t.Fatal("NewContourFromConfigMap() =", err)
+	}
+	if got, want := cfg.RootProxyNamespace, "contour-roots"; got != want {
+		t.Errorf("RootProxyNamespace = %q, wanted %q", got, want)
+	}
+
+	cm.Data[rootProxyNamespaceKey] = "Not_A_Namespace"
+	if _, err := NewContourFromConfigMap(cm); err == nil {
+		t.Errorf("expected an error parsing %s %q", rootProxyNamespaceKey, cm.Data[rootProxyNamespaceKey])
+	}
+}
+
 func TestConfigurationErrors(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -539,6 +1330,64 @@ extra:
 ExternalIP:
   service: foo/bar/extra
   class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "multiple services",
+		wantErr: false,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  services:
+  - foo/bar
+  - foo/baz
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "no service",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  services: []
+  class: baz
+ClusterLocal:
+  service: blah/bleh
+  class: bloop`,
+			},
+		},
+	}, {
+		name:    "bad key in services",
+		wantErr: true,
+		config: &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: system.Namespace(),
+				Name:      ContourConfigName,
+			},
+			Data: map[string]string{
+				visibilityConfigKey: `
+ExternalIP:
+  services:
+  - foo/bar
+  - foo/bar/extra
+  class: baz
 ClusterLocal:
   service: blah/bleh
   class: bloop`,
@@ -92,6 +92,82 @@ func (in *Contour) DeepCopyInto(out *Contour) {
 		*out = new(v1.CORSPolicy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DefaultTLSSecrets != nil {
+		in, out := &in.DefaultTLSSecrets, &out.DefaultTLSSecrets
+		*out = make(map[string]types.NamespacedName, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.AllowedBackendNamespaces != nil {
+		in, out := &in.AllowedBackendNamespaces, &out.AllowedBackendNamespaces
+		*out = make(sets.Set[string], len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.VisibilityAddresses != nil {
+		in, out := &in.VisibilityAddresses, &out.VisibilityAddresses
+		*out = make(map[v1alpha1.IngressVisibility]LoadBalancerAddress, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.VisibilityProbePorts != nil {
+		in, out := &in.VisibilityProbePorts, &out.VisibilityProbePorts
+		*out = make(map[v1alpha1.IngressVisibility]ProbePorts, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(v1.RetryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LocalRateLimit != nil {
+		in, out := &in.LocalRateLimit, &out.LocalRateLimit
+		*out = new(v1.LocalRateLimitPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LocalRateLimitVisibilities != nil {
+		in, out := &in.LocalRateLimitVisibilities, &out.LocalRateLimitVisibilities
+		*out = make(sets.Set[v1alpha1.IngressVisibility], len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.GlobalRateLimitDescriptors != nil {
+		in, out := &in.GlobalRateLimitDescriptors, &out.GlobalRateLimitDescriptors
+		*out = make([]v1.RateLimitDescriptor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IPFilters != nil {
+		in, out := &in.IPFilters, &out.IPFilters
+		*out = make(map[v1alpha1.IngressVisibility]IPFilter, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.JWTProviders != nil {
+		in, out := &in.JWTProviders, &out.JWTProviders
+		*out = make(map[string]v1.JWTProvider, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.DefaultAuthorization != nil {
+		in, out := &in.DefaultAuthorization, &out.DefaultAuthorization
+		*out = new(v1.AuthorizationServer)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DownstreamTLS != nil {
+		in, out := &in.DownstreamTLS, &out.DownstreamTLS
+		*out = new(DownstreamTLS)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -104,3 +180,84 @@ func (in *Contour) DeepCopy() *Contour {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownstreamTLS) DeepCopyInto(out *DownstreamTLS) {
+	*out = *in
+	if in.ClientValidation != nil {
+		in, out := &in.ClientValidation, &out.ClientValidation
+		*out = new(v1.DownstreamValidation)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownstreamTLS.
+func (in *DownstreamTLS) DeepCopy() *DownstreamTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(DownstreamTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPFilter) DeepCopyInto(out *IPFilter) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPFilter.
+func (in *IPFilter) DeepCopy() *IPFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(IPFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerAddress) DeepCopyInto(out *LoadBalancerAddress) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerAddress.
+func (in *LoadBalancerAddress) DeepCopy() *LoadBalancerAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbePorts) DeepCopyInto(out *ProbePorts) {
+	*out = *in
+	out.HTTP = in.HTTP
+	out.HTTPS = in.HTTPS
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbePorts.
+func (in *ProbePorts) DeepCopy() *ProbePorts {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbePorts)
+	in.DeepCopyInto(out)
+	return out
+}
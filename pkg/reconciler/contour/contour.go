@@ -18,15 +18,24 @@ package contour
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
@@ -57,6 +66,7 @@ const (
 
 // Reconciler implements controller.Reconciler for Ingress resources.
 type Reconciler struct {
+	kubeClient    kubernetes.Interface
 	ingressClient ingressclientset.Interface
 	contourClient contourclientset.Interface
 
@@ -65,10 +75,25 @@ type Reconciler struct {
 	ingressLister networkingv1alpha1.IngressLister
 	serviceLister corev1listers.ServiceLister
 
+	// claimIndexer indexes the HTTPProxies by the host they claim, see domainClaimIndex.
+	claimIndexer cache.Indexer
+
 	statusManager status.Manager
 	tracker       tracker.Interface
+	events        *eventRecorder
+	enqueueAfter  func(interface{}, time.Duration)
 }
 
+const (
+	// probeTimeout is how long probing may take before we record that it has
+	// timed out on the kingress.
+	probeTimeout = 5 * time.Minute
+
+	// endpointsNotReadyDelay is how long we wait before probing again when Envoy
+	// has no ready endpoints, or none for an IP family of a dual-stack service.
+	endpointsNotReadyDelay = 5 * time.Second
+)
+
 var _ ingressreconciler.Interface = (*Reconciler)(nil)
 
 // ReconcileKind reconciles ingress resource.
@@ -80,6 +105,59 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 	)
 	cfg := config.FromContext(ctx)
 
+	if err := resources.ValidateAnnotations(ctx, ing); err != nil {
+		ing.GetConditionSet().Manage(&ing.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+			"InvalidAnnotation", err.Error())
+		ing.Status.MarkLoadBalancerNotReady()
+		return nil
+	}
+
+	info := resources.ServiceNames(ctx, ing)
+	serviceNames := sets.List(sets.KeySet(info))
+
+	// Backends in other namespaces are reached through bridge Services, which
+	// must exist before the endpoint probe or any HTTP Proxy refers to them.
+	bridges := sets.New[string]()
+	for _, name := range serviceNames {
+		si := info[name]
+		if si.Namespace == ing.Namespace {
+			continue
+		}
+		if !cfg.Contour.BackendNamespaceAllowed(ing.Namespace, si.Namespace) {
+			ing.GetConditionSet().Manage(&ing.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+				"BackendNamespaceNotAllowed", fmt.Sprintf("Service %s/%s is not in a namespace that backends are allowed in.", si.Namespace, si.Name))
+			ing.Status.MarkLoadBalancerNotReady()
+			// The namespace may have been allowed before, so stop routing into it.
+			if err := r.deleteStaleProxies(ctx, ing, sets.New[types.NamespacedName]()); err != nil {
+				return err
+			}
+			return r.deleteStaleBackendBridges(ctx, ing, sets.New[string]())
+		}
+		// Reconcile the kingress again when the backend appears or changes.
+		if err := r.tracker.TrackReference(tracker.Reference{
+			APIVersion: "v1",
+			Kind:       "Service",
+			Namespace:  si.Namespace,
+			Name:       si.Name,
+		}, ing); err != nil {
+			return err
+		}
+		svc, err := r.serviceLister.Services(si.Namespace).Get(si.Name)
+		if apierrs.IsNotFound(err) {
+			ing.GetConditionSet().Manage(&ing.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+				"BackendNotFound", fmt.Sprintf("Service %s/%s does not exist.", si.Namespace, si.Name))
+			ing.Status.MarkLoadBalancerNotReady()
+			return nil
+		} else if err != nil {
+			return err
+		}
+		bridge, err := r.reconcileBackendBridge(ctx, ing, svc)
+		if err != nil {
+			return err
+		}
+		bridges.Insert(bridge.Name)
+	}
+
 	// Track whether there is an endpoint probe kingress to clean up.
 	haveEndpointProbe := false
 
@@ -126,6 +204,8 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 				return err
 			}
 			logger.Debugf("Created endpoint probe: %#v", actualChIng.Spec)
+			r.events.Eventf(ctx, ing, corev1.EventTypeNormal, "EndpointProbeCreated",
+				"Created endpoint probe KIngress %q", actualChIng.Name)
 		} else if err != nil {
 			return err
 		} else if !equality.Semantic.DeepEqual(actualChIng.Spec, desiredChIng.Spec) { // Reconcile it.
@@ -164,23 +244,22 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 	}
 	logger = logger.With(zap.Bool("have-endpoint-probe", haveEndpointProbe))
 
-	info := resources.ServiceNames(ctx, ing)
-	serviceNames := sets.List(sets.KeySet(info))
 	serviceToProtocol := make(map[string]string, len(info))
 	logger = logger.With(zap.Strings("services", serviceNames))
 
 	// Establish the protocol for each Service, and ensure that their Endpoints are
 	// populated with Ready addresses before we reprogram Contour.
 	for _, name := range serviceNames {
+		si := info[name]
 		if err := r.tracker.TrackReference(tracker.Reference{
 			APIVersion: "v1",
 			Kind:       "Service",
-			Namespace:  ing.Namespace,
-			Name:       name,
+			Namespace:  si.Namespace,
+			Name:       si.Name,
 		}, ing); err != nil {
 			return err
 		}
-		svc, err := r.serviceLister.Services(ing.Namespace).Get(name)
+		svc, err := r.serviceLister.Services(si.Namespace).Get(si.Name)
 		if err != nil {
 			return err
 		}
@@ -201,60 +280,40 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 		}
 	}
 
-	for _, proxy := range resources.MakeHTTPProxies(ctx, ing, serviceToProtocol) {
-		selector := labels.Set(map[string]string{
-			resources.ParentKey:     proxy.Labels[resources.ParentKey],
-			resources.DomainHashKey: proxy.Labels[resources.DomainHashKey],
-			resources.ClassKey:      proxy.Labels[resources.ClassKey],
-		}).AsSelector()
-		matches, err := r.contourLister.HTTPProxies(ing.Namespace).List(selector)
-		if err != nil {
-			return err
-		}
-		if len(matches) == 0 {
-			proxy, err := r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Create(ctx, proxy, metav1.CreateOptions{})
-			if err != nil {
-				return err
-			}
-			logger.Debugf("Created http proxy: %#v", proxy)
-			continue
-		}
-		update := matches[0].DeepCopy()
-		update.Annotations = proxy.Annotations
-		update.Labels = proxy.Labels
-		update.Spec = proxy.Spec
-		if equality.Semantic.DeepEqual(matches[0], update) {
-			// Avoid updates that don't change anything.
-			continue
-		}
-		if _, err = r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+	// Root proxies are not garbage collected with the kingress, so it must not go
+	// away before the FinalizerReconciler has deleted them.
+	withRoots := cfg.Contour.RootProxyNamespace != ""
+	if withRoots {
+		if err := setRootProxiesFinalizer(ctx, r.ingressClient, ing, true); err != nil {
 			return err
 		}
-		if diff, err := kmp.SafeDiff(update, matches[0]); err == nil {
-			logger.Debug("Updated http proxy diff: ", diff)
-		} else {
-			logger.Warnw("Error diffing http proxy", zap.Error(err))
-		}
-		logger.Debugf("Updated http proxy: %#v", update)
 	}
-
-	// Before deleting old programming, check our cache to see whether there is anything to clean up.
-	if selector, err := labels.Parse(fmt.Sprintf("%s=%s,%s!=%d",
-		resources.ParentKey, ing.Name,
-		resources.GenerationKey, ing.Generation)); err != nil {
-		return err
-	} else if leftovers, err := r.contourLister.HTTPProxies(ing.Namespace).List(selector); err != nil {
+	result, err := r.reconcileProxies(ctx, ing, serviceToProtocol)
+	if err != nil {
 		return err
-	} else if len(leftovers) != 0 {
-		logger.Debugf("Deleting %d older http proxies.", len(leftovers))
-		for _, leftover := range leftovers {
-			logger.Debugf("Leftover: %#v.", leftover)
-		}
-		if err := r.contourClient.ProjectcontourV1().HTTPProxies(ing.Namespace).DeleteCollection(
-			ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: selector.String()}); err != nil {
+	}
+	if !withRoots {
+		// reconcileProxies deleted any root proxies that we made before.
+		if err := setRootProxiesFinalizer(ctx, r.ingressClient, ing, false); err != nil {
 			return err
 		}
 	}
+
+	if len(result.Conflicts) != 0 {
+		sort.Strings(result.Conflicts)
+		ing.GetConditionSet().Manage(&ing.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+			"DomainConflict", strings.Join(result.Conflicts, " "))
+		ing.Status.MarkLoadBalancerNotReady()
+		return nil
+	}
+	if len(result.Rejections) != 0 {
+		// Probing would never succeed, so surface Contour's verdict instead of waiting on it.
+		sort.Strings(result.Rejections)
+		ing.GetConditionSet().Manage(&ing.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+			"HTTPProxyInvalid", strings.Join(result.Rejections, "; "))
+		ing.Status.MarkLoadBalancerNotReady()
+		return nil
+	}
 	ing.Status.MarkNetworkConfigured()
 
 	ready := ing.IsReady()
@@ -273,10 +332,19 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 	} else {
 		var err error
 		ready, err = r.statusManager.IsReady(ctx, ing)
+		if errors.Is(err, errEndpointsNotReady) {
+			// The prober does not track Envoy's endpoints, so check them again later.
+			logger.Debugw("Envoy endpoints are not ready", zap.Error(err))
+			ready, err = false, nil
+			r.enqueueAfter(ing, endpointsNotReadyDelay)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to probe Ingress %s/%s: %w", ing.GetNamespace(), ing.GetName(), err)
 		}
 		logger.Debugf("Status prober returned %v.", ready)
+		if !ready {
+			r.checkProbeTimeout(ctx, ing)
+		}
 	}
 
 	if ready {
@@ -300,31 +368,423 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, ing *v1alpha1.Ingress) r
 				return err
 			}
 			logger.Debug("Deleted endpoint probe.")
+			r.events.Eventf(ctx, ing, corev1.EventTypeNormal, "EndpointProbeDeleted",
+				"Deleted endpoint probe KIngress %q", names.EndpointProbeIngress(ing))
 		} else {
 			logger.Debug("Keeping endpoint probe, not ready.")
 		}
 	}
+
+	// Bridges that are no longer referenced may still back the prior generation's
+	// HTTP Proxies (and its endpoint probe) until we have reached a steady state.
+	if ing.IsReady() {
+		if err := r.deleteStaleBackendBridges(ctx, ing, bridges); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// proxyResult holds why Contour does not serve parts of a KIngress.
+type proxyResult struct {
+	// Conflicts describes the hosts of the KIngress that another KIngress claimed first.
+	Conflicts []string
+	// Rejections describes the HTTPProxies that Contour rejected.
+	Rejections []string
+}
+
+// reconcileProxies creates or updates the HTTPProxies for the current generation
+// of the KIngress, deletes the others made for it, and returns why Contour does
+// not serve any of them.
+func (r *Reconciler) reconcileProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtocol map[string]string) (*proxyResult, error) {
+	logger := logging.FromContext(ctx)
+	result := &proxyResult{}
+
+	proxies := resources.MakeHTTPProxies(ctx, ing, serviceToProtocol)
+
+	// Contour rejects every HTTPProxy of a host that several root proxies claim,
+	// so we leave the hosts that other KIngresses claimed first to them.
+	lost := sets.New[string]()
+	for _, proxy := range proxies {
+		if proxy.Spec.VirtualHost == nil {
+			continue
+		}
+		winner, err := r.domainWinner(ing, proxy)
+		if err != nil {
+			return nil, err
+		}
+		if winner == nil {
+			continue
+		}
+		// Reconcile again once the other KIngress lets go of the host.
+		if err := r.tracker.TrackReference(tracker.Reference{
+			APIVersion: v1.SchemeGroupVersion.String(),
+			Kind:       "HTTPProxy",
+			Namespace:  winner.Namespace,
+			Name:       winner.Name,
+		}, ing); err != nil {
+			return nil, err
+		}
+		lost.Insert(domainClaim(proxy))
+		result.Conflicts = append(result.Conflicts, fmt.Sprintf("Host %q is already claimed by KIngress %s.",
+			proxy.Spec.VirtualHost.Fqdn, proxyParent(winner)))
+	}
+
+	// The proxies to keep, any other made for the KIngress is stale.
+	desired := sets.New[types.NamespacedName]()
+	for _, proxy := range proxies {
+		if lost.Has(domainClaim(proxy)) {
+			// Should we hold the host alongside the other KIngress, we give it up below.
+			continue
+		}
+		matches, err := r.contourLister.HTTPProxies(proxy.Namespace).List(proxySelector(proxy))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			proxy, err := r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Create(ctx, proxy, metav1.CreateOptions{})
+			if err != nil {
+				return nil, err
+			}
+			desired.Insert(types.NamespacedName{Namespace: proxy.Namespace, Name: proxy.Name})
+			logger.Debugf("Created http proxy: %#v", proxy)
+			r.events.Eventf(ctx, ing, corev1.EventTypeNormal, "Created", "Created HTTPProxy %s/%s", proxy.Namespace, proxy.Name)
+			continue
+		}
+		desired.Insert(types.NamespacedName{Namespace: matches[0].Namespace, Name: matches[0].Name})
+		update := matches[0].DeepCopy()
+		update.Annotations = proxy.Annotations
+		update.Labels = proxy.Labels
+		update.Spec = proxy.Spec
+		if equality.Semantic.DeepEqual(matches[0], update) {
+			// Avoid updates that don't change anything.
+			// Only an unchanged proxy has a status that reflects its current spec.
+			if msg, invalid := invalidProxyMessage(matches[0]); invalid {
+				result.Rejections = append(result.Rejections, msg)
+			}
+			continue
+		}
+		if _, err = r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+		if diff, err := kmp.SafeDiff(update, matches[0]); err == nil {
+			logger.Debug("Updated http proxy diff: ", diff)
+		} else {
+			logger.Warnw("Error diffing http proxy", zap.Error(err))
+		}
+		logger.Debugf("Updated http proxy: %#v", update)
+		r.events.Eventf(ctx, ing, corev1.EventTypeNormal, "Updated", "Updated HTTPProxy %s/%s", update.Namespace, update.Name)
+	}
+
+	if err := r.deleteStaleProxies(ctx, ing, desired); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// domainWinner returns the root proxy of another KIngress that claimed the host
+// of the given root proxy first, if any.  Claims are ordered by the creation of
+// their root proxies, and then by the namespace and name of their KIngress, so
+// that the KIngresses of a host agree on the winner.
+func (r *Reconciler) domainWinner(ing *v1alpha1.Ingress, proxy *v1.HTTPProxy) (*v1.HTTPProxy, error) {
+	objs, err := r.claimIndexer.ByIndex(domainClaimIndex, domainClaim(proxy))
+	if err != nil {
+		return nil, err
+	}
+
+	parent := types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name}
+	var ours, winner *v1.HTTPProxy
+	for _, obj := range objs {
+		claim := obj.(*v1.HTTPProxy)
+		// Only root proxies claim their host.
+		if claim.Spec.VirtualHost == nil {
+			continue
+		}
+		if proxyParent(claim) == parent {
+			ours = claim
+		} else if winner == nil || claimedBefore(claim, winner) {
+			winner = claim
+		}
+	}
+	if winner == nil || (ours != nil && claimedBefore(ours, winner)) {
+		return nil, nil
+	}
+	return winner, nil
+}
+
+// claimedBefore returns whether root proxy a claimed its host before root proxy b.
+func claimedBefore(a, b *v1.HTTPProxy) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	pa, pb := proxyParent(a), proxyParent(b)
+	if pa.Namespace != pb.Namespace {
+		return pa.Namespace < pb.Namespace
+	}
+	return pa.Name < pb.Name
+}
+
+// proxyParent returns the KIngress that the proxy was made for.
+func proxyParent(proxy *v1.HTTPProxy) types.NamespacedName {
+	namespace := proxy.Namespace
+	if ns, ok := proxy.Labels[resources.ParentNamespaceKey]; ok {
+		namespace = ns
+	}
+	return types.NamespacedName{Namespace: namespace, Name: proxy.Labels[resources.ParentKey]}
+}
+
+// domainClaim identifies the host that the proxy serves, in the Contour class of the proxy.
+func domainClaim(proxy *v1.HTTPProxy) string {
+	return proxy.Labels[resources.ClassKey] + "/" + proxy.Labels[resources.DomainHashKey]
+}
+
+// domainClaimIndex is the name of the index of the HTTPProxy informer that
+// holds the proxies by their domainClaim.
+const domainClaimIndex = "domainClaim"
+
+// indexDomainClaim indexes the proxies that serve a host by their domainClaim.
+func indexDomainClaim(obj interface{}) ([]string, error) {
+	proxy, ok := obj.(*v1.HTTPProxy)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := proxy.Labels[resources.DomainHashKey]; !ok {
+		return nil, nil
+	}
+	return []string{domainClaim(proxy)}, nil
+}
+
+// deleteStaleProxies deletes the proxies made for the KIngress that are not in the
+// desired set: those of older generations, and those of the hosts, classes or
+// root-proxy-namespace that the KIngress or our config no longer have.
+func (r *Reconciler) deleteStaleProxies(ctx context.Context, ing *v1alpha1.Ingress, desired sets.Set[types.NamespacedName]) error {
+	selector, err := labels.Parse(fmt.Sprintf("%s=%s,!%s", resources.ParentKey, ing.Name, resources.ParentNamespaceKey))
+	if err != nil {
+		return err
+	}
+	proxies, err := r.contourLister.HTTPProxies(ing.Namespace).List(selector)
+	if err != nil {
+		return err
+	}
+	roots, err := r.rootProxies(ing)
+	if err != nil {
+		return err
+	}
+
+	for _, proxy := range append(proxies, roots...) {
+		if desired.Has(types.NamespacedName{Namespace: proxy.Namespace, Name: proxy.Name}) {
+			continue
+		}
+		// Root proxies cannot be owned by the KIngress, the others must be.
+		if _, isRoot := proxy.Labels[resources.ParentNamespaceKey]; !isRoot && !metav1.IsControlledBy(proxy, ing) {
+			continue
+		}
+		if err := r.deleteProxy(ctx, proxy); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Infof("Deleted stale http proxy %s/%s", proxy.Namespace, proxy.Name)
+		r.events.Eventf(ctx, ing, corev1.EventTypeNormal, "Deleted", "Deleted HTTPProxy %s/%s", proxy.Namespace, proxy.Name)
+	}
+	return nil
+}
+
+// rootProxies returns the root proxies of the KIngress, in whichever namespace.
+func (r *Reconciler) rootProxies(ing *v1alpha1.Ingress) ([]*v1.HTTPProxy, error) {
+	return r.contourLister.List(rootProxySelector(ing))
+}
+
+func (r *Reconciler) deleteProxy(ctx context.Context, proxy *v1.HTTPProxy) error {
+	if err := r.contourClient.ProjectcontourV1().HTTPProxies(proxy.Namespace).Delete(ctx, proxy.Name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// proxySelector selects the existing HTTPProxy that the desired proxy replaces.
+// Root proxies are told apart by the namespace of their KIngress, which the
+// proxies in the KIngress's namespace do not need.
+func proxySelector(proxy *v1.HTTPProxy) labels.Selector {
+	selector := labels.SelectorFromSet(labels.Set{
+		resources.ParentKey:     proxy.Labels[resources.ParentKey],
+		resources.DomainHashKey: proxy.Labels[resources.DomainHashKey],
+		resources.ClassKey:      proxy.Labels[resources.ClassKey],
+	})
+	if ns, ok := proxy.Labels[resources.ParentNamespaceKey]; ok {
+		req, _ := labels.NewRequirement(resources.ParentNamespaceKey, selection.Equals, []string{ns})
+		return selector.Add(*req)
+	}
+	req, _ := labels.NewRequirement(resources.ParentNamespaceKey, selection.DoesNotExist, nil)
+	return selector.Add(*req)
+}
+
+// rootProxySelector selects the root proxies of the KIngress.
+func rootProxySelector(ing *v1alpha1.Ingress) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{
+		resources.ParentKey:          ing.Name,
+		resources.ParentNamespaceKey: ing.Namespace,
+	})
+}
+
+// invalidProxyMessage returns a description of why Contour rejected the given
+// HTTPProxy, and whether it was rejected at all.  Status that was computed for
+// an older generation of the proxy is ignored.
+func invalidProxyMessage(proxy *v1.HTTPProxy) (string, bool) {
+	switch proxy.Status.CurrentStatus {
+	case "invalid", "orphaned":
+	default:
+		return "", false
+	}
+
+	valid := proxy.Status.GetConditionFor(v1.ValidConditionType)
+	if valid != nil && valid.ObservedGeneration != proxy.Generation {
+		return "", false
+	}
+
+	var details []string
+	if valid != nil {
+		for _, cond := range valid.Errors {
+			details = append(details, fmt.Sprintf("%s: %s", cond.Reason, cond.Message))
+		}
+	}
+	if len(details) == 0 && proxy.Status.Description != "" {
+		details = append(details, proxy.Status.Description)
+	}
+	return fmt.Sprintf("HTTPProxy %q is %s: %s", proxy.Name, proxy.Status.CurrentStatus, strings.Join(details, ", ")), true
+}
+
+// checkProbeTimeout records an Event on the kingress once it has waited for
+// probing longer than probeTimeout, and otherwise reconciles the kingress
+// again by then.  Probing starts when the LoadBalancerReady condition becomes
+// Unknown, and so includes the endpoint probe.
+func (r *Reconciler) checkProbeTimeout(ctx context.Context, ing *v1alpha1.Ingress) {
+	waited := time.Duration(0)
+	if cond := ing.Status.GetCondition(v1alpha1.IngressConditionLoadBalancerReady); cond != nil &&
+		cond.Status == corev1.ConditionUnknown && !cond.LastTransitionTime.Inner.IsZero() {
+		waited = time.Since(cond.LastTransitionTime.Inner.Time)
+	}
+	if waited < probeTimeout {
+		r.enqueueAfter(ing, probeTimeout-waited)
+		return
+	}
+	r.events.Eventf(ctx, ing, corev1.EventTypeWarning, "ProbeTimedOut",
+		"Probing has not succeeded within %v", probeTimeout)
+}
+
+// reconcileBackendBridge ensures the ExternalName Service through which the
+// kingress reaches the given Service from another namespace exists.
+func (r *Reconciler) reconcileBackendBridge(ctx context.Context, ing *v1alpha1.Ingress, target *corev1.Service) (*corev1.Service, error) {
+	logger := logging.FromContext(ctx)
+
+	desired := resources.MakeBackendBridge(ing, target)
+	actual, err := r.serviceLister.Services(desired.Namespace).Get(desired.Name)
+	if apierrs.IsNotFound(err) {
+		actual, err = r.kubeClient.CoreV1().Services(desired.Namespace).Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		logger.Debugf("Created backend bridge: %#v", actual)
+		return actual, nil
+	} else if err != nil {
+		return nil, err
+	} else if !metav1.IsControlledBy(actual, ing) {
+		ing.Status.MarkResourceNotOwned("Service", desired.Name)
+		return nil, fmt.Errorf("ingress: %q does not own Service: %q", ing.Name, desired.Name)
+	}
+
+	update := actual.DeepCopy()
+	update.Labels = desired.Labels
+	update.Annotations = desired.Annotations
+	update.Spec.Type = desired.Spec.Type
+	update.Spec.ExternalName = desired.Spec.ExternalName
+	update.Spec.Ports = desired.Spec.Ports
+	if equality.Semantic.DeepEqual(actual, update) {
+		return actual, nil
+	}
+	if actual, err = r.kubeClient.CoreV1().Services(update.Namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	logger.Debugf("Updated backend bridge: %#v", actual)
+	return actual, nil
+}
+
+// deleteStaleBackendBridges deletes the bridge Services owned by the kingress
+// that are not in the desired set.
+func (r *Reconciler) deleteStaleBackendBridges(ctx context.Context, ing *v1alpha1.Ingress, desired sets.Set[string]) error {
+	svcs, err := r.serviceLister.Services(ing.Namespace).List(labels.SelectorFromSet(labels.Set{
+		resources.ParentKey: ing.Name,
+	}))
+	if err != nil {
+		return err
+	}
+	for _, svc := range svcs {
+		if desired.Has(svc.Name) || !metav1.IsControlledBy(svc, ing) {
+			continue
+		}
+		if err := r.kubeClient.CoreV1().Services(svc.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+			return err
+		}
+		logging.FromContext(ctx).Debugf("Deleted backend bridge: %s", svc.Name)
+	}
 	return nil
 }
 
 func (r *Reconciler) lbStatus(ctx context.Context, vis v1alpha1.IngressVisibility) (lbs []v1alpha1.LoadBalancerIngressStatus) {
 	logger := logging.FromContext(ctx)
+	cfg := config.FromContext(ctx).Contour
+
+	address := cfg.VisibilityAddresses[vis]
+	if address.Source == config.AddressSourceStatic {
+		return []v1alpha1.LoadBalancerIngressStatus{{
+			IP:     address.IP,
+			Domain: address.Domain,
+		}}
+	}
 
-	if keys, ok := config.FromContext(ctx).Contour.VisibilityKeys[vis]; ok {
+	if keys, ok := cfg.VisibilityKeys[vis]; ok {
 		for _, key := range sets.List(keys) {
 			namespace, name, _ := cache.SplitMetaNamespaceKey(key)
-			clusterIP := ""
+			domainInternal := network.GetServiceHostname(name, namespace)
 
-			if service, err := r.serviceLister.Services(namespace).Get(name); err == nil {
-				clusterIP = service.Spec.ClusterIP
-			} else {
+			service, err := r.serviceLister.Services(namespace).Get(name)
+			if err != nil {
 				logger.Infof("failed to get service to determine cluster IP", zap.Error(err))
+				lbs = append(lbs, v1alpha1.LoadBalancerIngressStatus{DomainInternal: domainInternal})
+				continue
 			}
 
-			lbs = append(lbs, v1alpha1.LoadBalancerIngressStatus{
-				IP:             clusterIP,
-				DomainInternal: network.GetServiceHostname(name, namespace),
-			})
+			var addresses []v1alpha1.LoadBalancerIngressStatus
+			switch address.Source {
+			case config.AddressSourceLoadBalancer:
+				for _, lb := range service.Status.LoadBalancer.Ingress {
+					addresses = append(addresses, v1alpha1.LoadBalancerIngressStatus{
+						IP:             lb.IP,
+						Domain:         lb.Hostname,
+						DomainInternal: domainInternal,
+					})
+				}
+			case config.AddressSourceExternalIPs:
+				for _, ip := range service.Spec.ExternalIPs {
+					addresses = append(addresses, v1alpha1.LoadBalancerIngressStatus{
+						IP:             ip,
+						DomainInternal: domainInternal,
+					})
+				}
+			}
+			if len(addresses) == 0 {
+				// Fall back on the ClusterIPs, one per IP family, until the service
+				// has been assigned the addresses we are looking for.
+				clusterIPs := service.Spec.ClusterIPs
+				if len(clusterIPs) == 0 {
+					clusterIPs = []string{service.Spec.ClusterIP}
+				}
+				for _, ip := range clusterIPs {
+					addresses = append(addresses, v1alpha1.LoadBalancerIngressStatus{
+						IP:             ip,
+						DomainInternal: domainInternal,
+					})
+				}
+			}
+			lbs = append(lbs, addresses...)
 		}
 	}
 	return lbs
@@ -20,24 +20,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	"knative.dev/pkg/logging"
 
 	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
 	fakeingressclient "knative.dev/networking/pkg/client/injection/client/fake"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	"knative.dev/networking/pkg/apis/networking"
@@ -92,6 +96,27 @@ func TestReconcile(t *testing.T) {
 				i.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
 			}),
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "EndpointProbeCreated", `Created endpoint probe KIngress "name--ep"`),
+		},
+	}, {
+		Name: "first reconcile basic ingress (invalid retry policy annotation)",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.RetryPolicyKey: "count: -2",
+			})),
+		}, servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, withAnnotation(map[string]string{
+				resources.RetryPolicyKey: "count: -2",
+			}), func(i *v1alpha1.Ingress) {
+				i.Status.InitializeConditions()
+				i.GetConditionSet().Manage(&i.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+					"InvalidAnnotation", "annotation "+resources.RetryPolicyKey+" is invalid: count must be -1 (to disable retries) or greater, got -2")
+				i.Status.MarkLoadBalancerNotReady()
+			}),
+		}},
 	}, {
 		Name:    "first reconcile basic ingress (failure creating prober)",
 		Key:     "ns/name",
@@ -143,6 +168,10 @@ func TestReconcile(t *testing.T) {
 			},
 			Name: "name--ep",
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
 	}, {
 		Name:    "failure deleting endpoints probe",
 		Key:     "ns/name",
@@ -179,6 +208,7 @@ func TestReconcile(t *testing.T) {
 			Name: "name--ep",
 		}},
 		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
 			Eventf(corev1.EventTypeWarning, "InternalError", "inducing failure for delete ingresses"),
 		},
 	}, {
@@ -248,12 +278,85 @@ func TestReconcile(t *testing.T) {
 			},
 			Name: "name--ep",
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
 	}, {
 		Name: "steady state basic ingress (no probe)",
 		Key:  "ns/name",
 		Objects: append(append([]runtime.Object{
 			ing("name", "ns", withBasicSpec, withContour, makeItReady),
 		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+	}, {
+		Name: "steady state basic ingress (invalid proxy)",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour), withInvalidStatus)...), servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, makeItReady, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.GetConditionSet().Manage(&i.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+					"HTTPProxyInvalid", `HTTPProxy "name--example.com" is invalid: DuplicateVhost: fqdn "example.com" is used in multiple HTTPProxies`)
+				i.Status.MarkLoadBalancerNotReady()
+			}),
+		}},
+	}, {
+		Name: "steady state basic ingress (stale invalid proxy)",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour), withInvalidStatus, func(p *v1.HTTPProxy) {
+			p.Generation = 2
+		})...), servicesAndEndpoints...),
+	}, {
+		Name: "host claimed by another ingress",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			mustMakeProbe(t, ing("name", "ns", withBasicSpec, withContour), makeItReady),
+		}, mustMakeProxies(t, ing("name", "other", withBasicSpec, withContour))...), servicesAndEndpoints...),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.GetConditionSet().Manage(&i.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+					"DomainConflict", `Host "example.com" is already claimed by KIngress other/name.`)
+				i.Status.MarkLoadBalancerNotReady()
+			}),
+		}},
+	}, {
+		Name: "host claimed by both ingresses, the other claimed first",
+		Key:  "ns/name",
+		Objects: append(append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...),
+			mustMakeProxies(t, ing("name", "a-ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))[0].(*v1.HTTPProxy).Name,
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, makeItReady, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.GetConditionSet().Manage(&i.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+					"DomainConflict", `Host "example.com" is already claimed by KIngress a-ns/name.`)
+				i.Status.MarkLoadBalancerNotReady()
+			}),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Deleted", "Deleted HTTPProxy ns/name--example.com"),
+		},
+	}, {
+		Name: "host claimed by both ingresses, we claimed first",
+		Key:  "ns/name",
+		Objects: append(append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...),
+			mustMakeProxies(t, ing("name", "other", withBasicSpec, withContour))...), servicesAndEndpoints...),
 	}, {
 		Name: "basic ingress changed",
 		Key:  "ns/name",
@@ -273,13 +376,6 @@ func TestReconcile(t *testing.T) {
 			},
 			Name: "name--ep",
 		}},
-		WantDeleteCollections: []clientgotesting.DeleteCollectionActionImpl{{
-			ListRestrictions: clientgotesting.ListRestrictions{
-				// We delete the things that don't match the generation being reconciled.
-				Labels: deleteSelector(t, 1),
-				Fields: fields.Everything(),
-			},
-		}},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: ing("name", "ns", withContour, withGeneration(1), withBasicSpec2, func(i *v1alpha1.Ingress) {
 				// These are the things we expect to change in status.
@@ -300,6 +396,10 @@ func TestReconcile(t *testing.T) {
 				i.Status.ObservedGeneration = 1
 			}),
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Updated", "Updated HTTPProxy ns/name--example.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
 	}, {
 		Name: "first reconcile multi-httpproxy ingress",
 		Key:  "ns/name",
@@ -331,6 +431,11 @@ func TestReconcile(t *testing.T) {
 			},
 			Name: "name--ep",
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--foo.com"),
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--bar.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
 	}, {
 		Name:    "error creating http proxy",
 		Key:     "ns/name",
@@ -380,38 +485,62 @@ func TestReconcile(t *testing.T) {
 			Eventf(corev1.EventTypeWarning, "InternalError", "inducing failure for update httpproxies"),
 		},
 	}, {
-		Name:    "error deleting collection",
+		Name: "stale http proxy of a removed class",
+		Key:  "ns/name",
+		Objects: append(append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...),
+			mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour), withClass("old-class"))...), servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: "name-old-class-example.com",
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Deleted", "Deleted HTTPProxy ns/name-old-class-example.com"),
+		},
+	}, {
+		Name: "stale root proxy of a removed root-proxy-namespace",
+		Key:  "ns/name",
+		// Root proxies are deleted outside of the kingress's namespace.
+		SkipNamespaceValidation: true,
+		Objects: append(append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady, withRootProxiesFinalizer),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...),
+			mustMakeProxiesWithConfig(t, ing("name", "ns", withBasicSpec, withContour), rootProxyConfig)[1]), servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "contour-roots",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: "example.com",
+		}},
+		WantPatches: []clientgotesting.PatchActionImpl{finalizerPatch("ns", "name")},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Deleted", "Deleted HTTPProxy contour-roots/example.com"),
+		},
+	}, {
+		Name:    "error deleting stale http proxy",
 		Key:     "ns/name",
 		WantErr: true,
 		WithReactors: []clientgotesting.ReactionFunc{
-			InduceFailure("delete-collection", "httpproxies"),
+			InduceFailure("delete", "httpproxies"),
 		},
-		Objects: append(append([]runtime.Object{
-			ing("name", "ns", withContour, withGeneration(1), withBasicSpec2),
-			mustMakeProbe(t, ing("name", "ns", withBasicSpec2, withContour, withGeneration(1)), makeItReady),
-		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...), servicesAndEndpoints...),
-		WantUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: mustMakeProxies(t,
-				ing("name", "ns", withContour, withGeneration(1), withBasicSpec2),
-			)[0],
-		}},
-		WantDeleteCollections: []clientgotesting.DeleteCollectionActionImpl{{
-			ListRestrictions: clientgotesting.ListRestrictions{
-				// We delete the things that don't match the generation being reconciled.
-				Labels: deleteSelector(t, 1),
-				Fields: fields.Everything(),
+		Objects: append(append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}, mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour))...),
+			mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour), withClass("old-class"))...), servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
 			},
-		}},
-		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: ing("name", "ns", withContour, withGeneration(1), withBasicSpec2, func(i *v1alpha1.Ingress) {
-				// These are the things we expect to change in status.
-				i.Status.InitializeConditions()
-				i.Status.ObservedGeneration = 1
-				i.Status.MarkIngressNotReady("NewObservedGenFailure", "unsuccessfully observed a new generation")
-			}),
+			Name: "name-old-class-example.com",
 		}},
 		WantEvents: []string{
-			Eventf(corev1.EventTypeWarning, "InternalError", "inducing failure for delete-collection httpproxies"),
+			Eventf(corev1.EventTypeWarning, "InternalError", "inducing failure for delete httpproxies"),
 		},
 	}, {
 		Name:    "error updating status",
@@ -449,6 +578,8 @@ func TestReconcile(t *testing.T) {
 			Name: "name--ep",
 		}},
 		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
 			Eventf(corev1.EventTypeWarning, "UpdateFailed", `Failed to update status for "name": inducing failure for update ingresses`),
 		},
 	}, {
@@ -471,12 +602,16 @@ func TestReconcile(t *testing.T) {
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
 			ingressClient: fakeingressclient.Get(ctx),
 			contourClient: fakecontourclient.Get(ctx),
 			ingressLister: listers.GetIngressLister(),
 			contourLister: listers.GetHTTPProxyLister(),
 			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
 			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter:  func(interface{}, time.Duration) {},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return true, nil
@@ -512,6 +647,9 @@ func TestReconcileInternalEncryption(t *testing.T) {
 				i.Status.MarkIngressNotReady("EndpointsNotReady", "Waiting for Envoys to receive Endpoints data.")
 			}),
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "EndpointProbeCreated", `Created endpoint probe KIngress "name--ep"`),
+		},
 	}, {
 		Name: "first reconcile basic ingress (endpoints probe succeeded)",
 		Key:  "ns/name",
@@ -543,6 +681,10 @@ func TestReconcileInternalEncryption(t *testing.T) {
 			},
 			Name: "name--ep",
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--foo.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
 	}, {
 		Name: "first reconcile domainmapping ingress (endpoints probe succeeded)",
 		Key:  "ns/dm-name",
@@ -574,16 +716,24 @@ func TestReconcileInternalEncryption(t *testing.T) {
 			},
 			Name: "dm-name--ep",
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/dm-name--dm.example.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "dm-name--ep"`),
+		},
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
 			ingressClient: fakeingressclient.Get(ctx),
 			contourClient: fakecontourclient.Get(ctx),
 			ingressLister: listers.GetIngressLister(),
 			contourLister: listers.GetHTTPProxyLister(),
 			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
 			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter:  func(interface{}, time.Duration) {},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return true, nil
@@ -600,6 +750,229 @@ func TestReconcileInternalEncryption(t *testing.T) {
 	}))
 }
 
+func TestReconcileCrossNamespace(t *testing.T) {
+	withPrivateBackend := func(i *v1alpha1.Ingress) {
+		i.Spec.Rules[0].HTTP.Paths[0].Splits[0].ServiceNamespace = "private"
+	}
+	notAllowed := func(i *v1alpha1.Ingress) {
+		i.Status.InitializeConditions()
+		i.GetConditionSet().Manage(&i.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+			"BackendNamespaceNotAllowed", "Service private/backend is not in a namespace that backends are allowed in.")
+		i.Status.MarkLoadBalancerNotReady()
+	}
+
+	// KIngress validation rejects backends in other namespaces, so the fake
+	// clients refuse status updates for these; start from the expected status.
+	table := TableTest{{
+		Name: "first reconcile cross-namespace ingress (endpoints probe succeeded)",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withCrossNamespaceSpec, withContour, makeItReady),
+			mustMakeProbeWithConfig(t, ing("name", "ns", withCrossNamespaceSpec, withContour), crossNamespaceConfig, makeItReady),
+			sharedService,
+		}, servicesAndEndpoints...),
+		WantCreates: append(mustMakeProxiesWithConfig(t, ing("name", "ns", withCrossNamespaceSpec, withContour), crossNamespaceConfig),
+			resources.MakeBackendBridge(ing("name", "ns"), sharedService)),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1alpha1.SchemeGroupVersion.WithResource("ingresses"),
+			},
+			Name: "name--ep",
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
+	}, {
+		Name: "steady state cross-namespace ingress, stale bridge",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withCrossNamespaceSpec, withContour, makeItReady),
+			sharedService,
+			resources.MakeBackendBridge(ing("name", "ns"), sharedService),
+			resources.MakeBackendBridge(ing("name", "ns"), &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "shared",
+					Name:      "old",
+				},
+			}),
+		}, mustMakeProxiesWithConfig(t, ing("name", "ns", withCrossNamespaceSpec, withContour), crossNamespaceConfig)...),
+			servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  corev1.SchemeGroupVersion.WithResource("services"),
+			},
+			Name: "name-old-shared",
+		}},
+	}, {
+		Name: "cross-namespace ingress not allowed",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withCrossNamespaceSpec, withPrivateBackend, withContour, notAllowed),
+		}, servicesAndEndpoints...),
+	}, {
+		Name: "cross-namespace ingress no longer allowed, bridge and proxies deleted",
+		Key:  "ns/name",
+		Objects: append(append([]runtime.Object{
+			ing("name", "ns", withCrossNamespaceSpec, withPrivateBackend, withContour, notAllowed),
+			resources.MakeBackendBridge(ing("name", "ns"), &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "private",
+					Name:      "backend",
+				},
+			}),
+		}, mustMakeProxiesWithConfig(t, ing("name", "ns", withCrossNamespaceSpec, withPrivateBackend, withContour), crossNamespaceConfig)...),
+			servicesAndEndpoints...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: "name--example.com",
+		}, {
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  corev1.SchemeGroupVersion.WithResource("services"),
+			},
+			Name: "name-backend-private",
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Deleted", "Deleted HTTPProxy ns/name--example.com"),
+		},
+	}, {
+		Name: "cross-namespace backend not found",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withCrossNamespaceSpec, withContour, func(i *v1alpha1.Ingress) {
+				i.Status.InitializeConditions()
+				i.GetConditionSet().Manage(&i.Status).MarkFalse(v1alpha1.IngressConditionNetworkConfigured,
+					"BackendNotFound", "Service shared/backend does not exist.")
+				i.Status.MarkLoadBalancerNotReady()
+			}),
+		}, servicesAndEndpoints...),
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
+			ingressClient: fakeingressclient.Get(ctx),
+			contourClient: fakecontourclient.Get(ctx),
+			ingressLister: listers.GetIngressLister(),
+			contourLister: listers.GetHTTPProxyLister(),
+			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
+			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter:  func(interface{}, time.Duration) {},
+			statusManager: &fakeStatusManager{
+				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+					return true, nil
+				},
+			},
+		}
+
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: crossNamespaceConfig,
+				},
+			})
+	}))
+}
+
+func TestReconcileRootProxies(t *testing.T) {
+	proxies := mustMakeProxiesWithConfig(t, ing("name", "ns", withBasicSpec, withContour), rootProxyConfig)
+	var staleRoots []runtime.Object
+	for _, obj := range proxies {
+		if p := obj.(*v1.HTTPProxy); p.Namespace == "contour-roots" {
+			stale := p.DeepCopy()
+			stale.Namespace = "old-roots"
+			staleRoots = append(staleRoots, stale)
+		}
+	}
+
+	table := TableTest{{
+		Name: "first reconcile basic ingress (endpoints probe succeeded)",
+		Key:  "ns/name",
+		// Root proxies are created outside of the kingress's namespace.
+		SkipNamespaceValidation: true,
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			mustMakeProbeWithConfig(t, ing("name", "ns", withBasicSpec, withContour), rootProxyConfig, makeItReady),
+		}, servicesAndEndpoints...),
+		WantCreates: proxies,
+		WantPatches: []clientgotesting.PatchActionImpl{finalizerPatch("ns", "name", rootProxiesFinalizer)},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, makeItReady),
+		}},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "ns",
+				Resource:  v1alpha1.SchemeGroupVersion.WithResource("ingresses"),
+			},
+			Name: "name--ep",
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy contour-roots/example.com"),
+			Eventf(corev1.EventTypeNormal, "EndpointProbeDeleted", `Deleted endpoint probe KIngress "name--ep"`),
+		},
+	}, {
+		Name:                    "steady state, root proxies moved",
+		Key:                     "ns/name",
+		SkipNamespaceValidation: true,
+		Objects: append(append(append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, makeItReady, withRootProxiesFinalizer),
+		}, proxies...), staleRoots...), servicesAndEndpoints...),
+		WantDeletes: func() (deletes []clientgotesting.DeleteActionImpl) {
+			for _, obj := range staleRoots {
+				deletes = append(deletes, clientgotesting.DeleteActionImpl{
+					ActionImpl: clientgotesting.ActionImpl{
+						Namespace: "old-roots",
+						Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+					},
+					Name: obj.(*v1.HTTPProxy).Name,
+				})
+			}
+			return deletes
+		}(),
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Deleted", "Deleted HTTPProxy old-roots/example.com"),
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
+			ingressClient: fakeingressclient.Get(ctx),
+			contourClient: fakecontourclient.Get(ctx),
+			ingressLister: listers.GetIngressLister(),
+			contourLister: listers.GetHTTPProxyLister(),
+			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
+			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter:  func(interface{}, time.Duration) {},
+			statusManager: &fakeStatusManager{
+				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+					return true, nil
+				},
+			},
+		}
+
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: rootProxyConfig,
+				},
+			})
+	}))
+}
+
 func TestReconcileProberNotReady(t *testing.T) {
 	table := TableTest{{
 		Name: "first reconcile basic ingress",
@@ -617,16 +990,23 @@ func TestReconcileProberNotReady(t *testing.T) {
 				i.Status.MarkLoadBalancerNotReady()
 			}),
 		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
+		},
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
 			ingressClient: fakeingressclient.Get(ctx),
 			contourClient: fakecontourclient.Get(ctx),
 			ingressLister: listers.GetIngressLister(),
 			contourLister: listers.GetHTTPProxyLister(),
 			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
 			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter:  func(interface{}, time.Duration) {},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return false, nil
@@ -643,6 +1023,65 @@ func TestReconcileProberNotReady(t *testing.T) {
 	}))
 }
 
+func TestReconcileEndpointsNotReady(t *testing.T) {
+	var requeued time.Duration
+	table := TableTest{{
+		Name: "first reconcile basic ingress",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour),
+			mustMakeProbe(t, ing("name", "ns", withBasicSpec, withContour), makeItReady),
+		}, servicesAndEndpoints...),
+		WantCreates: mustMakeProxies(t, ing("name", "ns", withBasicSpec, withContour)),
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: ing("name", "ns", withBasicSpec, withContour, func(i *v1alpha1.Ingress) {
+				// These are the things we expect to change in status.
+				i.Status.InitializeConditions()
+				i.Status.MarkNetworkConfigured()
+				i.Status.MarkLoadBalancerNotReady()
+			}),
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
+			ingressClient: fakeingressclient.Get(ctx),
+			contourClient: fakecontourclient.Get(ctx),
+			ingressLister: listers.GetIngressLister(),
+			contourLister: listers.GetHTTPProxyLister(),
+			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
+			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter: func(_ interface{}, delay time.Duration) {
+				if requeued == 0 || delay < requeued {
+					requeued = delay
+				}
+			},
+			statusManager: &fakeStatusManager{
+				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
+					return false, fmt.Errorf("%w: no ready IPv6 endpoints", errEndpointsNotReady)
+				},
+			},
+		}
+		return ingressreconciler.NewReconciler(ctx, logging.FromContext(ctx), fakeingressclient.Get(ctx),
+			listers.GetIngressLister(), controller.GetEventRecorder(ctx), r, ContourIngressClassName,
+			controller.Options{
+				ConfigStore: &testConfigStore{
+					config: defaultConfig,
+				},
+			})
+	}))
+
+	if requeued != endpointsNotReadyDelay {
+		t.Errorf("Requeued after %v, wanted %v", requeued, endpointsNotReadyDelay)
+	}
+}
+
 func TestReconcileProbeError(t *testing.T) {
 	theError := errors.New("this is the error")
 
@@ -663,18 +1102,23 @@ func TestReconcileProbeError(t *testing.T) {
 			}),
 		}},
 		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Created", "Created HTTPProxy ns/name--example.com"),
 			Eventf(corev1.EventTypeWarning, "InternalError", fmt.Sprintf("failed to probe Ingress ns/name: %v", theError)),
 		},
 	}}
 
 	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
 		r := &Reconciler{
+			kubeClient:    fakekubeclient.Get(ctx),
 			ingressClient: fakeingressclient.Get(ctx),
 			contourClient: fakecontourclient.Get(ctx),
 			ingressLister: listers.GetIngressLister(),
 			contourLister: listers.GetHTTPProxyLister(),
 			serviceLister: listers.GetK8sServiceLister(),
+			claimIndexer:  claimIndexer(listers),
 			tracker:       &NullTracker{},
+			events:        newEventRecorder(),
+			enqueueAfter:  func(interface{}, time.Duration) {},
 			statusManager: &fakeStatusManager{
 				FakeIsReady: func(context.Context, *v1alpha1.Ingress) (bool, error) {
 					return false, theError
@@ -698,6 +1142,184 @@ func TestReconcileProbeError(t *testing.T) {
 	}))
 }
 
+func TestCheckProbeTimeout(t *testing.T) {
+	probingSince := func(d time.Duration) IngressOption {
+		return func(i *v1alpha1.Ingress) {
+			i.Status.InitializeConditions()
+			i.Status.MarkLoadBalancerNotReady()
+			for j := range i.Status.Conditions {
+				if i.Status.Conditions[j].Type == v1alpha1.IngressConditionLoadBalancerReady {
+					i.Status.Conditions[j].LastTransitionTime.Inner = metav1.NewTime(time.Now().Add(-d))
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		ing        *v1alpha1.Ingress
+		wantAfter  time.Duration
+		wantEvents int
+	}{{
+		name:      "probing just started",
+		ing:       ing("name", "ns"),
+		wantAfter: probeTimeout,
+	}, {
+		name:      "probing for a while",
+		ing:       ing("name", "ns", probingSince(2*time.Minute)),
+		wantAfter: probeTimeout - 2*time.Minute,
+	}, {
+		name:       "probing timed out",
+		ing:        ing("name", "ns", probingSince(probeTimeout+time.Minute)),
+		wantEvents: 1,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(1)
+			ctx := controller.WithEventRecorder(context.Background(), recorder)
+
+			var gotAfter time.Duration
+			r := &Reconciler{
+				events: newEventRecorder(),
+				enqueueAfter: func(_ interface{}, after time.Duration) {
+					gotAfter = after
+				},
+			}
+			r.checkProbeTimeout(ctx, test.ing)
+
+			// Allow for the time that passed since the condition was backdated.
+			if diff := test.wantAfter - gotAfter; diff < 0 || diff > time.Second {
+				t.Errorf("Enqueued after %v, wanted %v", gotAfter, test.wantAfter)
+			}
+			if got := len(recorder.Events); got != test.wantEvents {
+				t.Errorf("Recorded %d events, wanted %d", got, test.wantEvents)
+			}
+		})
+	}
+}
+
+func TestLBStatus(t *testing.T) {
+	publicService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      publicName,
+			Namespace: publicNS,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:        corev1.ServiceTypeLoadBalancer,
+			ClusterIP:   publicSvcIP,
+			ExternalIPs: []string{"203.0.113.1"},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{
+					IP: "198.51.100.1",
+				}, {
+					Hostname: "lb.example.com",
+				}},
+			},
+		},
+	}
+	pendingService := publicService.DeepCopy()
+	pendingService.Spec.ExternalIPs = nil
+	pendingService.Status = corev1.ServiceStatus{}
+	dualStackService := publicService.DeepCopy()
+	dualStackService.Spec.ClusterIPs = []string{publicSvcIP, "fd00::1"}
+
+	tests := []struct {
+		name    string
+		address config.LoadBalancerAddress
+		service *corev1.Service
+		want    []v1alpha1.LoadBalancerIngressStatus
+	}{{
+		name:    "cluster ip",
+		service: publicService,
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			IP:             publicSvcIP,
+			DomainInternal: publicSvc,
+		}},
+	}, {
+		name:    "dual-stack cluster ips",
+		service: dualStackService,
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			IP:             publicSvcIP,
+			DomainInternal: publicSvc,
+		}, {
+			IP:             "fd00::1",
+			DomainInternal: publicSvc,
+		}},
+	}, {
+		name:    "load balancer",
+		address: config.LoadBalancerAddress{Source: config.AddressSourceLoadBalancer},
+		service: publicService,
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			IP:             "198.51.100.1",
+			DomainInternal: publicSvc,
+		}, {
+			Domain:         "lb.example.com",
+			DomainInternal: publicSvc,
+		}},
+	}, {
+		name:    "load balancer pending",
+		address: config.LoadBalancerAddress{Source: config.AddressSourceLoadBalancer},
+		service: pendingService,
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			IP:             publicSvcIP,
+			DomainInternal: publicSvc,
+		}},
+	}, {
+		name:    "external ips",
+		address: config.LoadBalancerAddress{Source: config.AddressSourceExternalIPs},
+		service: publicService,
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			IP:             "203.0.113.1",
+			DomainInternal: publicSvc,
+		}},
+	}, {
+		name: "static",
+		address: config.LoadBalancerAddress{
+			Source: config.AddressSourceStatic,
+			IP:     "192.0.2.1",
+			Domain: "ingress.example.com",
+		},
+		service: pendingService,
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			IP:     "192.0.2.1",
+			Domain: "ingress.example.com",
+		}},
+	}, {
+		name:    "missing service",
+		address: config.LoadBalancerAddress{Source: config.AddressSourceLoadBalancer},
+		want: []v1alpha1.LoadBalancerIngressStatus{{
+			DomainInternal: publicSvc,
+		}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if test.service != nil {
+				objects = append(objects, test.service)
+			}
+			tl := NewListers(objects)
+			r := &Reconciler{
+				serviceLister: tl.GetK8sServiceLister(),
+			}
+
+			cfg := defaultConfig.DeepCopy()
+			cfg.Contour.VisibilityAddresses = map[v1alpha1.IngressVisibility]config.LoadBalancerAddress{
+				v1alpha1.IngressVisibilityExternalIP: test.address,
+			}
+			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
+
+			got := r.lbStatus(ctx, v1alpha1.IngressVisibilityExternalIP)
+			if !cmp.Equal(test.want, got) {
+				t.Error("lbStatus (-want, +got) =", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
 var (
 	publicNS      = "public-contour"
 	publicName    = "envoy-stuff"
@@ -717,6 +1339,24 @@ var (
 			},
 		},
 	}
+	crossNamespaceConfig = &config.Config{
+		Contour: &config.Contour{
+			VisibilityKeys: map[v1alpha1.IngressVisibility]sets.Set[string]{
+				v1alpha1.IngressVisibilityClusterLocal: sets.New(privateKey),
+				v1alpha1.IngressVisibilityExternalIP:   sets.New(publicKey),
+			},
+			AllowedBackendNamespaces: sets.New("shared"),
+		},
+	}
+	rootProxyConfig = &config.Config{
+		Contour: &config.Contour{
+			VisibilityKeys: map[v1alpha1.IngressVisibility]sets.Set[string]{
+				v1alpha1.IngressVisibilityClusterLocal: sets.New(privateKey),
+				v1alpha1.IngressVisibilityExternalIP:   sets.New(publicKey),
+			},
+			RootProxyNamespace: "contour-roots",
+		},
+	}
 	internalEncryptionConfig = &config.Config{
 		Contour: &config.Contour{
 			VisibilityKeys: map[v1alpha1.IngressVisibility]sets.Set[string]{
@@ -856,6 +1496,19 @@ var (
 	}
 	tlsServiceAndEndpoint = append(append([]runtime.Object{}, tlsService...), tlsEndpoint...)
 
+	sharedService = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "shared",
+			Name:      "backend",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{
+				Name: "http",
+				Port: 80,
+			}},
+		},
+	}
+
 	h2cServiceName    = "doo"
 	tlsServiceName    = "tlsService"
 	serviceToProtocol = map[string]string{
@@ -883,13 +1536,31 @@ func mustMakeProxiesWithConfig(t *testing.T, i *v1alpha1.Ingress, cfg *config.Co
 	return objs
 }
 
-func deleteSelector(t *testing.T, generation int) labels.Selector {
-	l, err := labels.Parse(fmt.Sprintf("%s=name,%s!=%d",
-		resources.ParentKey, resources.GenerationKey, generation))
-	if err != nil {
-		t.Fatal("labels.Parse() =", err)
+func withClass(class string) HTTPProxyOption {
+	return func(p *v1.HTTPProxy) {
+		p.Name = kmeta.ChildName(p.Labels[resources.ParentKey]+"-"+class+"-", p.Spec.VirtualHost.Fqdn)
+		p.Labels[resources.ClassKey] = class
+		p.Annotations[resources.ClassKey] = class
 	}
-	return l
+}
+
+func withInvalidStatus(p *v1.HTTPProxy) {
+	p.Status.CurrentStatus = "invalid"
+	p.Status.Description = "At least one error present, see Errors for details"
+	p.Status.Conditions = []v1.DetailedCondition{{
+		Condition: metav1.Condition{
+			Type:               v1.ValidConditionType,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: p.Generation,
+			Reason:             "ErrorPresent",
+		},
+		Errors: []v1.SubCondition{{
+			Type:    v1.ConditionTypeVirtualHostError,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DuplicateVhost",
+			Message: `fqdn "example.com" is used in multiple HTTPProxies`,
+		}},
+	}}
 }
 
 type IngressOption func(*v1alpha1.Ingress)
@@ -979,6 +1650,28 @@ func withBasicSpec2(i *v1alpha1.Ingress) {
 	}
 }
 
+func withCrossNamespaceSpec(i *v1alpha1.Ingress) {
+	i.Spec = v1alpha1.IngressSpec{
+		HTTPOption: v1alpha1.HTTPOptionEnabled,
+		Rules: []v1alpha1.IngressRule{{
+			Hosts:      []string{"example.com"},
+			Visibility: v1alpha1.IngressVisibilityExternalIP,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{{
+					Splits: []v1alpha1.IngressBackendSplit{{
+						IngressBackend: v1alpha1.IngressBackend{
+							ServiceName:      "backend",
+							ServiceNamespace: "shared",
+							ServicePort:      intstr.FromInt(80),
+						},
+						Percent: 100,
+					}},
+				}},
+			},
+		}},
+	}
+}
+
 func withHTTPRedirected(i *v1alpha1.Ingress) {
 	i.Spec.HTTPOption = v1alpha1.HTTPOptionRedirected
 }
@@ -1063,6 +1756,33 @@ func withGeneration(gen int64) IngressOption {
 	}
 }
 
+// claimIndexer returns the HTTPProxy indexer of the listers with the domain claim
+// index, as NewController sets it up on the informer.
+func claimIndexer(listers *Listers) cache.Indexer {
+	indexer := listers.IndexerFor(&v1.HTTPProxy{})
+	if err := indexer.AddIndexers(cache.Indexers{domainClaimIndex: indexDomainClaim}); err != nil {
+		panic(err)
+	}
+	return indexer
+}
+
+// finalizerPatch is the patch that sets the finalizers of the KIngress.
+func finalizerPatch(namespace, name string, finalizers ...string) clientgotesting.PatchActionImpl {
+	action := clientgotesting.PatchActionImpl{}
+	action.Namespace = namespace
+	action.Name = name
+	quoted := make([]string, 0, len(finalizers))
+	for _, f := range finalizers {
+		quoted = append(quoted, strconv.Quote(f))
+	}
+	action.Patch = []byte(`{"metadata":{"finalizers":[` + strings.Join(quoted, ",") + `],"resourceVersion":""}}`)
+	return action
+}
+
+func withRootProxiesFinalizer(i *v1alpha1.Ingress) {
+	i.Finalizers = append(i.Finalizers, rootProxiesFinalizer)
+}
+
 func withContour(i *v1alpha1.Ingress) {
 	withAnnotation(map[string]string{
 		networking.IngressClassAnnotationKey: ContourIngressClassName,
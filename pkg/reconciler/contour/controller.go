@@ -24,11 +24,13 @@ import (
 	ingressclient "knative.dev/networking/pkg/client/injection/client"
 	ingressinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress"
 	ingressreconciler "knative.dev/networking/pkg/client/injection/reconciler/networking/v1alpha1/ingress"
-	endpointsinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/endpoints"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	podinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/pod"
 	serviceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/service"
+	endpointsliceinformer "knative.dev/pkg/client/injection/kube/informers/discovery/v1/endpointslice"
 
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	"knative.dev/networking/pkg/apis/networking"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	networkcfg "knative.dev/networking/pkg/config"
@@ -39,7 +41,10 @@ import (
 	"knative.dev/pkg/reconciler"
 	"knative.dev/pkg/tracker"
 
+	contourv1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -50,20 +55,29 @@ func NewController(
 ) *controller.Impl {
 	logger := logging.FromContext(ctx)
 
-	endpointsInformer := endpointsinformer.Get(ctx)
+	endpointSliceInformer := endpointsliceinformer.Get(ctx)
 	serviceInformer := serviceinformer.Get(ctx)
 	ingressInformer := ingressinformer.Get(ctx)
 	proxyInformer := proxyinformer.Get(ctx)
 	podInformer := podinformer.Get(ctx)
 
 	c := &Reconciler{
+		kubeClient:    kubeclient.Get(ctx),
 		ingressClient: ingressclient.Get(ctx),
 		contourClient: contourclient.Get(ctx),
 		contourLister: proxyInformer.Lister(),
 		ingressLister: ingressInformer.Lister(),
 		serviceLister: serviceInformer.Lister(),
+		claimIndexer:  proxyInformer.Informer().GetIndexer(),
+		events:        newEventRecorder(),
+	}
+	if err := proxyInformer.Informer().AddIndexers(cache.Indexers{
+		domainClaimIndex: indexDomainClaim,
+	}); err != nil {
+		logger.Panicw("Failed to add the domain claim index to the HTTPProxy informer", zap.Error(err))
 	}
 	myFilterFunc := reconciler.AnnotationFilterFunc(networking.IngressClassAnnotationKey, ContourIngressClassName, false)
+	var configStore *config.Store
 	impl := ingressreconciler.NewImpl(ctx, c, ContourIngressClassName,
 		func(impl *controller.Impl) controller.Options {
 			configsToResync := []interface{}{
@@ -74,7 +88,7 @@ func NewController(
 			resyncIngressesOnConfigChange := configmap.TypeFilter(configsToResync...)(func(string, interface{}) {
 				impl.FilteredGlobalResync(myFilterFunc, ingressInformer.Informer())
 			})
-			configStore := config.NewStore(logger.Named("config-store"), resyncIngressesOnConfigChange)
+			configStore = config.NewStore(logger.Named("config-store"), resyncIngressesOnConfigChange)
 			configStore.WatchConfigs(cmw)
 			return controller.Options{
 				ConfigStore:       configStore,
@@ -98,20 +112,56 @@ func NewController(
 		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
 	})
 
+	// Root proxies live in the root-proxy-namespace, away from the kingress that they serve.
+	proxyInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: reconciler.LabelExistsFilterFunc(resources.ParentNamespaceKey),
+		Handler: controller.HandleAll(impl.EnqueueLabelOfNamespaceScopedResource(
+			resources.ParentNamespaceKey, resources.ParentKey)),
+	})
+
+	// Enqueue us if any of the Services bridging to backends in other namespaces change.
+	serviceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterController(&v1alpha1.Ingress{}),
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	// Resync our kingresses when the addresses of an Envoy service change,
+	// since they may be reported in the kingress status.
+	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSvc, newSvc := oldObj.(*corev1.Service), newObj.(*corev1.Service)
+			if equality.Semantic.DeepEqual(oldSvc.Status, newSvc.Status) &&
+				equality.Semantic.DeepEqual(oldSvc.Spec.ExternalIPs, newSvc.Spec.ExternalIPs) &&
+				equality.Semantic.DeepEqual(oldSvc.Spec.ClusterIPs, newSvc.Spec.ClusterIPs) &&
+				oldSvc.Spec.ClusterIP == newSvc.Spec.ClusterIP {
+				return
+			}
+			key := newSvc.Namespace + "/" + newSvc.Name
+			for _, keys := range configStore.Load().Contour.VisibilityKeys {
+				if keys.Has(key) {
+					impl.FilteredGlobalResync(myFilterFunc, ingressInformer.Informer())
+					return
+				}
+			}
+		},
+	})
+
 	statusProber := status.NewProber(
 		logger.Named("status-manager"),
 		&lister{
-			ServiceLister:   serviceInformer.Lister(),
-			EndpointsLister: endpointsInformer.Lister(),
+			ServiceLister:       serviceInformer.Lister(),
+			EndpointSliceLister: endpointSliceInformer.Lister(),
 		},
 		func(ia *v1alpha1.Ingress) { impl.Enqueue(ia) })
 	c.statusManager = statusProber
+	c.enqueueAfter = impl.EnqueueAfter
 	statusProber.Start(ctx.Done())
 
 	ingressInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		// Cancel probing when an Ingress is deleted
 		DeleteFunc: statusProber.CancelIngressProbing,
 	})
+
 	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		// Cancel probing when a Pod is deleted
 		DeleteFunc: statusProber.CancelPodProbing,
@@ -128,6 +178,13 @@ func NewController(
 			corev1.SchemeGroupVersion.WithKind("Service"),
 		),
 	))
+	// KIngresses that lost a host to another track the HTTPProxy that claimed it.
+	proxyInformer.Informer().AddEventHandler(controller.HandleAll(
+		controller.EnsureTypeMeta(
+			c.tracker.OnChanged,
+			contourv1.SchemeGroupVersion.WithKind("HTTPProxy"),
+		),
+	))
 
 	return impl
 }
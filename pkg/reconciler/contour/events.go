@@ -0,0 +1,73 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/utils/lru"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/controller"
+)
+
+const (
+	// A KIngress may record a burst of eventBurst Events with the same reason,
+	// and then another one every eventInterval, so that global resyncs do not
+	// flood the API server with Events.
+	eventBurst    = 10
+	eventInterval = time.Minute
+
+	// maxEventLimiters bounds the number of rate limiters that are kept, one
+	// per KIngress and reason.
+	maxEventLimiters = 4096
+)
+
+// eventRecorder records rate-limited Events on KIngresses.
+type eventRecorder struct {
+	mu       sync.Mutex
+	limiters *lru.Cache
+}
+
+func newEventRecorder() *eventRecorder {
+	return &eventRecorder{limiters: lru.New(maxEventLimiters)}
+}
+
+// Eventf records an Event on the KIngress with the recorder of the context,
+// unless the KIngress has recorded too many Events with the reason lately.
+func (e *eventRecorder) Eventf(ctx context.Context, ing *v1alpha1.Ingress, eventtype, reason, messageFmt string, args ...interface{}) {
+	recorder := controller.GetEventRecorder(ctx)
+	if recorder == nil || !e.allow(ing, reason) {
+		return
+	}
+	recorder.Eventf(ing, eventtype, reason, messageFmt, args...)
+}
+
+func (e *eventRecorder) allow(ing *v1alpha1.Ingress, reason string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := ing.Namespace + "/" + ing.Name + "/" + reason
+	limiter, ok := e.limiters.Get(key)
+	if !ok {
+		limiter = rate.NewLimiter(rate.Every(eventInterval), eventBurst)
+		e.limiters.Add(key, limiter)
+	}
+	return limiter.(*rate.Limiter).Allow()
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/controller"
+)
+
+func TestEventRecorderRateLimit(t *testing.T) {
+	recorder := record.NewFakeRecorder(3 * eventBurst)
+	ctx := controller.WithEventRecorder(context.Background(), recorder)
+	e := newEventRecorder()
+
+	ingA := ing("a", "ns")
+	ingB := ing("b", "ns")
+	for i := 0; i < eventBurst+5; i++ {
+		e.Eventf(ctx, ingA, corev1.EventTypeNormal, "Created", "Created HTTPProxy %d", i)
+		e.Eventf(ctx, ingA, corev1.EventTypeNormal, "Updated", "Updated HTTPProxy %d", i)
+		e.Eventf(ctx, ingB, corev1.EventTypeNormal, "Created", "Created HTTPProxy %d", i)
+	}
+
+	// Each KIngress and reason gets its own burst, the rest are dropped.
+	if got, want := len(recorder.Events), 3*eventBurst; got != want {
+		t.Errorf("Recorded %d events, wanted %d", got, want)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"encoding/json"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
+	contourclient "knative.dev/net-contour/pkg/client/injection/client"
+	proxyinformer "knative.dev/net-contour/pkg/client/injection/informers/projectcontour/v1/httpproxy"
+	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	ingressclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	ingressclient "knative.dev/networking/pkg/client/injection/client"
+	ingressinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress"
+	networkingv1alpha1 "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
+
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+// rootProxiesFinalizer is the finalizer that keeps a kingress around until its
+// root proxies, which garbage collection leaves behind, are deleted.  The
+// generated reconciler would put its own finalizer on every kingress, so we
+// only put this one on those that may have root proxies.
+const rootProxiesFinalizer = ContourIngressClassName + "/root-proxies"
+
+// NewFinalizerController returns a new controller that deletes the root proxies
+// of the kingresses that are being deleted.
+func NewFinalizerController(
+	ctx context.Context,
+	_ configmap.Watcher,
+) *controller.Impl {
+	logger := logging.FromContext(ctx)
+
+	ingressInformer := ingressinformer.Get(ctx)
+	proxyInformer := proxyinformer.Get(ctx)
+
+	c := &FinalizerReconciler{
+		ingressClient: ingressclient.Get(ctx),
+		contourClient: contourclient.Get(ctx),
+		ingressLister: ingressInformer.Lister(),
+		contourLister: proxyInformer.Lister(),
+	}
+	impl := controller.NewContext(ctx, c, controller.ControllerOptions{
+		WorkQueueName: "RootProxyFinalizer",
+		Logger:        logger,
+	})
+
+	c.PromoteFunc = func(bkt reconciler.Bucket, enq func(reconciler.Bucket, types.NamespacedName)) error {
+		ings, err := c.ingressLister.List(labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, ing := range ings {
+			if hasRootProxiesFinalizer(ing) {
+				enq(bkt, types.NamespacedName{Namespace: ing.Namespace, Name: ing.Name})
+			}
+		}
+		return nil
+	}
+
+	ingressInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: hasRootProxiesFinalizer,
+		Handler:    controller.HandleAll(impl.Enqueue),
+	})
+
+	return impl
+}
+
+// FinalizerReconciler deletes the root proxies of the kingresses that are being
+// deleted, and then removes their rootProxiesFinalizer.
+type FinalizerReconciler struct {
+	reconciler.LeaderAwareFuncs
+
+	ingressClient ingressclientset.Interface
+	contourClient contourclientset.Interface
+
+	// Listers index properties about resources
+	ingressLister networkingv1alpha1.IngressLister
+	contourLister contourlisters.HTTPProxyLister
+}
+
+var _ controller.Reconciler = (*FinalizerReconciler)(nil)
+
+// Reconcile implements controller.Reconciler
+func (r *FinalizerReconciler) Reconcile(ctx context.Context, key string) error {
+	logger := logging.FromContext(ctx)
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Errorf("Invalid resource key: %s", key)
+		return nil
+	}
+	if !r.IsLeaderFor(types.NamespacedName{Namespace: namespace, Name: name}) {
+		return nil
+	}
+
+	ing, err := r.ingressLister.Ingresses(namespace).Get(name)
+	if apierrs.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if ing.DeletionTimestamp.IsZero() || !hasRootProxiesFinalizer(ing) {
+		return nil
+	}
+
+	roots, err := r.contourLister.List(rootProxySelector(ing))
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if err := r.contourClient.ProjectcontourV1().HTTPProxies(root.Namespace).Delete(ctx, root.Name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+			return err
+		}
+		logger.Infof("Deleted root http proxy %s/%s", root.Namespace, root.Name)
+	}
+	return setRootProxiesFinalizer(ctx, r.ingressClient, ing.DeepCopy(), false)
+}
+
+// hasRootProxiesFinalizer returns whether the object is a kingress with the
+// rootProxiesFinalizer.
+func hasRootProxiesFinalizer(obj interface{}) bool {
+	ing, ok := obj.(*v1alpha1.Ingress)
+	if !ok {
+		return false
+	}
+	for _, f := range ing.Finalizers {
+		if f == rootProxiesFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// setRootProxiesFinalizer adds the rootProxiesFinalizer to the kingress, or
+// removes it, unless it already has it or not.  The kingress is updated with
+// the result.
+func setRootProxiesFinalizer(ctx context.Context, client ingressclientset.Interface, ing *v1alpha1.Ingress, want bool) error {
+	if hasRootProxiesFinalizer(ing) == want {
+		return nil
+	}
+
+	finalizers := []string{}
+	for _, f := range ing.Finalizers {
+		if f != rootProxiesFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if want {
+		finalizers = append(finalizers, rootProxiesFinalizer)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": ing.ResourceVersion,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	updated, err := client.NetworkingV1alpha1().Ingresses(ing.Namespace).Patch(ctx, ing.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	ing.Finalizers = updated.Finalizers
+	ing.ResourceVersion = updated.ResourceVersion
+	return nil
+}
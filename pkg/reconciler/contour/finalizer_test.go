@@ -0,0 +1,118 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package contour
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+
+	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	fakeingressclient "knative.dev/networking/pkg/client/injection/client/fake"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+func TestReconcileFinalizer(t *testing.T) {
+	proxies := mustMakeProxiesWithConfig(t, ing("name", "ns", withBasicSpec, withContour), rootProxyConfig)
+
+	table := TableTest{{
+		Name: "bad workqueue key",
+		Key:  "too/many/parts",
+	}, {
+		Name: "key not found",
+		Key:  "foo/not-found",
+	}, {
+		Name: "ingress not marked for deletion",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, withRootProxiesFinalizer),
+		}, proxies...),
+	}, {
+		Name: "ingress marked for deletion, without our finalizer",
+		Key:  "ns/name",
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, markedForDeletion),
+		}, proxies...),
+	}, {
+		Name: "ingress marked for deletion, root proxies deleted",
+		Key:  "ns/name",
+		// Root proxies are deleted outside of the kingress's namespace.
+		SkipNamespaceValidation: true,
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, markedForDeletion, func(i *v1alpha1.Ingress) {
+				i.Finalizers = []string{"other", rootProxiesFinalizer}
+			}),
+		}, proxies...),
+		// The proxies in the kingress's namespace are garbage collected.
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "contour-roots",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: "example.com",
+		}},
+		WantPatches: []clientgotesting.PatchActionImpl{finalizerPatch("ns", "name", "other")},
+	}, {
+		Name: "ingress marked for deletion, root proxies already deleted",
+		Key:  "ns/name",
+		Objects: []runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, markedForDeletion, withRootProxiesFinalizer),
+		},
+		WantPatches: []clientgotesting.PatchActionImpl{finalizerPatch("ns", "name")},
+	}, {
+		Name:    "failure deleting root proxies",
+		Key:     "ns/name",
+		WantErr: true,
+		WithReactors: []clientgotesting.ReactionFunc{
+			InduceFailure("delete", "httpproxies"),
+		},
+		SkipNamespaceValidation: true,
+		Objects: append([]runtime.Object{
+			ing("name", "ns", withBasicSpec, withContour, markedForDeletion, withRootProxiesFinalizer),
+		}, proxies...),
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "contour-roots",
+				Resource:  v1.SchemeGroupVersion.WithResource("httpproxies"),
+			},
+			Name: "example.com",
+		}},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		return &FinalizerReconciler{
+			ingressClient: fakeingressclient.Get(ctx),
+			contourClient: fakecontourclient.Get(ctx),
+			ingressLister: listers.GetIngressLister(),
+			contourLister: listers.GetHTTPProxyLister(),
+		}
+	}))
+}
+
+func markedForDeletion(i *v1alpha1.Ingress) {
+	i.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+}
@@ -18,23 +18,36 @@ package contour
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	discoveryv1listers "k8s.io/client-go/listers/discovery/v1"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/ingress"
 	"knative.dev/networking/pkg/k8s"
 	"knative.dev/networking/pkg/status"
 )
 
+// errEndpointsNotReady is returned when an Envoy service has no ready endpoints
+// yet, or none to probe for one of its IP families.  The prober would consider a kingress
+// without anything to probe ready, so this reports it as not ready instead.
+var errEndpointsNotReady = errors.New("endpoints not ready")
+
 type lister struct {
-	ServiceLister   corev1listers.ServiceLister
-	EndpointsLister corev1listers.EndpointsLister
+	ServiceLister       corev1listers.ServiceLister
+	EndpointSliceLister discoveryv1listers.EndpointSliceLister
 }
 
 var _ status.ProbeTargetLister = (*lister)(nil)
@@ -46,13 +59,22 @@ func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([
 	cfg := config.FromContext(ctx)
 	visibilityKeys := cfg.Contour.VisibilityKeys
 
-	for key, hosts := range ingress.HostsPerVisibility(ing, visibilityKeys) {
-		port, scheme := int32(80), "http"
+	hostsPerKey := ingress.HostsPerVisibility(ing, visibilityKeys)
+	// Each Envoy service is probed separately, in a deterministic order.
+	for _, key := range sets.List(sets.KeySet(hostsPerKey)) {
+		hosts := hostsPerKey[key]
+		vis := v1alpha1.IngressVisibilityExternalIP
+		if visibilityKeys[v1alpha1.IngressVisibilityClusterLocal].Has(key) {
+			vis = v1alpha1.IngressVisibilityClusterLocal
+		}
+		ports := cfg.Contour.ProbePortsFor(vis)
+		port, scheme := ports.HTTP, "http"
 
-		// Probe external servce with https.
+		// Probe external servce with https, unless it requires client certificates.
 		if ing.Spec.HTTPOption == v1alpha1.HTTPOptionRedirected &&
-			!visibilityKeys["ClusterLocal"].Has(key) {
-			port, scheme = 443, "https"
+			vis == v1alpha1.IngressVisibilityExternalIP &&
+			!resources.RequiresClientCertificate(ctx, ing) {
+			port, scheme = ports.HTTPS, "https"
 		}
 
 		namespace, name, err := cache.SplitMetaNamespaceKey(key)
@@ -65,9 +87,14 @@ func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([
 			return nil, fmt.Errorf("failed to get Service: %w", err)
 		}
 
-		endpoints, err := l.EndpointsLister.Endpoints(namespace).Get(name)
+		slices, err := l.EndpointSliceLister.EndpointSlices(namespace).List(labels.SelectorFromSet(labels.Set{
+			discoveryv1.LabelServiceName: name,
+		}))
 		if err != nil {
-			return nil, fmt.Errorf("failed to get Endpoints: %w", err)
+			return nil, fmt.Errorf("failed to list EndpointSlices: %w", err)
+		}
+		if len(slices) == 0 {
+			return nil, fmt.Errorf("%w: no EndpointSlices found for %s/%s", errEndpointsNotReady, namespace, name)
 		}
 
 		urls := make([]*url.URL, 0, hosts.Len())
@@ -78,29 +105,113 @@ func (l *lister) ListProbeTargets(ctx context.Context, ing *v1alpha1.Ingress) ([
 			})
 		}
 
-		portName, err := k8s.NameForPortNumber(service, port)
+		servicePort, err := lookupPort(service, port)
 		if err != nil {
-			return nil, fmt.Errorf("failed to lookup port %d in %s/%s: %w", port, namespace, name, err)
+			return nil, fmt.Errorf("failed to lookup port %s in %s/%s: %w", port.String(), namespace, name, err)
 		}
-		for _, sub := range endpoints.Subsets {
-			podPort, err := k8s.PortNumberForName(sub, portName)
+		portName := servicePort.Name
+
+		// Each IP family of the Service is probed separately, so that a dual-stack
+		// Service is only ready once Envoy answers on both.
+		families := probedFamilies(service)
+		var missing []string
+		for _, family := range families {
+			podIPs, err := podIPsByPort(slices, discoveryv1.AddressType(family), portName)
 			if err != nil {
-				return nil, fmt.Errorf("failed to lookup port name %q in endpoints subset for %s/%s: %w",
+				return nil, fmt.Errorf("failed to lookup port name %q in EndpointSlices for %s/%s: %w",
 					portName, namespace, name, err)
 			}
-
-			pt := status.ProbeTarget{
-				PodIPs:  sets.New[string](),
-				Port:    strconv.Itoa(int(port)),
-				PodPort: strconv.Itoa(int(podPort)),
-				URLs:    urls,
+			if len(podIPs) == 0 {
+				missing = append(missing, string(family))
+				continue
 			}
-			for _, addr := range sub.Addresses {
-				pt.PodIPs.Insert(addr.IP)
+			// The pods of a Service may listen on different ports during a rollout.
+			for _, podPort := range sets.List(sets.KeySet(podIPs)) {
+				results = append(results, status.ProbeTarget{
+					PodIPs:  podIPs[podPort],
+					Port:    strconv.Itoa(int(servicePort.Port)),
+					PodPort: strconv.Itoa(int(podPort)),
+					URLs:    urls,
+				})
 			}
-			results = append(results, pt)
+		}
+		if len(missing) != 0 && len(service.Spec.IPFamilies) > 1 {
+			return nil, fmt.Errorf("%w: no ready %s endpoints found for dual-stack Service %s/%s",
+				errEndpointsNotReady, strings.Join(missing, ", "), namespace, name)
+		}
+		if len(missing) == len(families) {
+			return nil, fmt.Errorf("%w: no ready endpoints found for Service %s/%s", errEndpointsNotReady, namespace, name)
 		}
 	}
 
 	return results, nil
 }
+
+// lookupPort finds the port of the Service with the given number or name.
+func lookupPort(service *corev1.Service, port intstr.IntOrString) (*corev1.ServicePort, error) {
+	if port.Type == intstr.Int {
+		name, err := k8s.NameForPortNumber(service, port.IntVal)
+		if err != nil {
+			return nil, err
+		}
+		return &corev1.ServicePort{Name: name, Port: port.IntVal}, nil
+	}
+	for i := range service.Spec.Ports {
+		if service.Spec.Ports[i].Name == port.StrVal {
+			return &service.Spec.Ports[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no port with name %q found", port.StrVal)
+}
+
+// probedFamilies returns the IP families of the Service, which the API server
+// assigns according to its ipFamilyPolicy.  Both are probed when the Service
+// predates them.
+func probedFamilies(service *corev1.Service) []corev1.IPFamily {
+	if len(service.Spec.IPFamilies) == 0 {
+		return []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol}
+	}
+	return service.Spec.IPFamilies
+}
+
+// podIPsByPort merges the EndpointSlices of a Service with the given address type
+// into the addresses of its ready endpoints, by the port that they serve the named
+// port of the Service on. Endpoints that are terminating are left out, even while
+// they still serve.
+func podIPsByPort(slices []*discoveryv1.EndpointSlice, addressType discoveryv1.AddressType, portName string) (map[int32]sets.Set[string], error) {
+	podIPs := make(map[int32]sets.Set[string])
+	for _, slice := range slices {
+		if slice.AddressType != addressType {
+			continue
+		}
+		if len(slice.Endpoints) == 0 {
+			continue
+		}
+
+		var podPort *int32
+		for _, p := range slice.Ports {
+			if p.Name != nil && *p.Name == portName && p.Port != nil {
+				podPort = p.Port
+				break
+			}
+		}
+		if podPort == nil {
+			return nil, fmt.Errorf("no port for name %q found in EndpointSlice %s", portName, slice.Name)
+		}
+
+		for _, ep := range slice.Endpoints {
+			// A nil condition is to be read as ready, and as not terminating.
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			if ep.Conditions.Terminating != nil && *ep.Conditions.Terminating {
+				continue
+			}
+			if _, ok := podIPs[*podPort]; !ok {
+				podIPs[*podPort] = sets.New[string]()
+			}
+			podIPs[*podPort].Insert(ep.Addresses...)
+		}
+	}
+	return podIPs, nil
+}
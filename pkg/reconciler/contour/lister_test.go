@@ -18,24 +18,31 @@ package contour
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/status"
+	"knative.dev/pkg/ptr"
 
 	"github.com/google/go-cmp/cmp"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
 	. "knative.dev/net-contour/pkg/reconciler/testing"
 )
 
 func TestListProbeTargets(t *testing.T) {
 	tests := []struct {
 		name    string
+		config  *config.Config
 		ing     *v1alpha1.Ingress
 		objects []runtime.Object
 		want    []status.ProbeTarget
@@ -45,8 +52,8 @@ func TestListProbeTargets(t *testing.T) {
 		objects: []runtime.Object{
 			publicService,
 			privateService,
-			publicEndpointsOneAddr,
-			privateEndpointsNoAddr,
+			publicSliceOneAddr,
+			privateSliceNoAddr,
 		},
 		ing: ing("name", "ns", withBasicSpec, withContour),
 		want: []status.ProbeTarget{{
@@ -63,8 +70,8 @@ func TestListProbeTargets(t *testing.T) {
 		objects: []runtime.Object{
 			publicSecureService,
 			privateService,
-			publicEndpointsOneAddr,
-			privateEndpointsNoAddr,
+			publicSliceOneAddr,
+			privateSliceNoAddr,
 		},
 		ing: ing("name", "ns", withBasicSpec, withContour, withHTTPRedirected),
 		want: []status.ProbeTarget{{
@@ -77,13 +84,32 @@ func TestListProbeTargets(t *testing.T) {
 			}},
 		}},
 	}, {
-		name: "public with multiple addresses and subsets to probe",
+		name: "public with client certificates required (https redirected)",
 		objects: []runtime.Object{
 			publicService,
 			privateService,
-			publicEndpointsMultiAddrMultiSubset,
-			privateEndpointsNoAddr,
+			publicSliceOneAddr,
+			privateSliceNoAddr,
 		},
+		ing: ing("name", "ns", withBasicSpec, withContour, withHTTPRedirected, withAnnotation(map[string]string{
+			resources.ClientCASecretKey: "client-ca",
+		})),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "public with multiple addresses and ports to probe",
+		objects: append([]runtime.Object{
+			publicService,
+			privateService,
+			privateSliceNoAddr,
+		}, publicSlicesMultiPort...),
 		ing: ing("name", "ns", withBasicSpec, withContour),
 		want: []status.ProbeTarget{{
 			PodIPs:  sets.New("2.3.4.5"),
@@ -102,27 +128,218 @@ func TestListProbeTargets(t *testing.T) {
 				Host:   "example.com",
 			}},
 		}},
+	}, {
+		name:   "public with multiple envoy services",
+		config: multiServiceConfig,
+		objects: []runtime.Object{
+			publicService,
+			publicServiceB,
+			privateService,
+			publicSliceOneAddr,
+			publicSliceB,
+			privateSliceNoAddr,
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}, {
+			PodIPs:  sets.New("5.6.7.8"),
+			Port:    "80",
+			PodPort: "5678",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "slices of a port are merged, without unready or terminating endpoints",
+		objects: []runtime.Object{
+			publicService,
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234,
+				readyEndpoint("1.2.3.4"),
+				discoveryv1.Endpoint{
+					Addresses:  []string{"1.2.3.5"},
+					Conditions: discoveryv1.EndpointConditions{Ready: ptr.Bool(false)},
+				}),
+			endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv4, "asdf", 1234,
+				// Ready is unset by some controllers, and then means ready.
+				discoveryv1.Endpoint{Addresses: []string{"2.3.4.5"}},
+				discoveryv1.Endpoint{
+					Addresses: []string{"2.3.4.6"},
+					Conditions: discoveryv1.EndpointConditions{
+						Serving:     ptr.Bool(true),
+						Terminating: ptr.Bool(true),
+					},
+				}),
+			// Slices of other Services are ignored.
+			endpointSlice(publicNS, "other", "a", discoveryv1.AddressTypeIPv4, "asdf", 1234, readyEndpoint("9.9.9.9")),
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("1.2.3.4", "2.3.4.5"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "dual-stack",
+		objects: []runtime.Object{
+			withIPFamilies(publicService, corev1.IPv4Protocol, corev1.IPv6Protocol),
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234, readyEndpoint("1.2.3.4")),
+			endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv6, "asdf", 1234, readyEndpoint("2001:db8::1")),
+			endpointSlice(publicNS, publicName, "c", discoveryv1.AddressTypeFQDN, "asdf", 1234, readyEndpoint("envoy.example.com")),
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("1.2.3.4"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}, {
+			PodIPs:  sets.New("2001:db8::1"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name: "dual-stack with a family not ready",
+		objects: []runtime.Object{
+			withIPFamilies(publicService, corev1.IPv6Protocol, corev1.IPv4Protocol),
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234, readyEndpoint("1.2.3.4")),
+			endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv6, "asdf", 1234),
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		wantErr: fmt.Errorf("%w: no ready IPv6 endpoints found for dual-stack Service %s/%s",
+			errEndpointsNotReady, publicNS, publicName),
+	}, {
+		name: "dual-stack with no family ready",
+		objects: []runtime.Object{
+			withIPFamilies(publicService, corev1.IPv4Protocol, corev1.IPv6Protocol),
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234),
+			endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv6, "asdf", 1234),
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		wantErr: fmt.Errorf("%w: no ready IPv4, IPv6 endpoints found for dual-stack Service %s/%s",
+			errEndpointsNotReady, publicNS, publicName),
+	}, {
+		name: "single-stack with no endpoints ready",
+		objects: []runtime.Object{
+			withIPFamilies(publicService, corev1.IPv4Protocol),
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234,
+				discoveryv1.Endpoint{
+					Addresses:  []string{"1.2.3.4"},
+					Conditions: discoveryv1.EndpointConditions{Ready: ptr.Bool(false)},
+				}),
+			endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv4, "asdf", 1234),
+			// Endpoints of the other family are not probed.
+			endpointSlice(publicNS, publicName, "c", discoveryv1.AddressTypeIPv6, "asdf", 1234, readyEndpoint("2001:db8::1")),
+		},
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		wantErr: fmt.Errorf("%w: no ready endpoints found for Service %s/%s", errEndpointsNotReady, publicNS, publicName),
+	}, {
+		name: "single-stack IPv6",
+		objects: []runtime.Object{
+			withIPFamilies(publicService, corev1.IPv6Protocol),
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234, readyEndpoint("1.2.3.4")),
+			endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv6, "asdf", 1234, readyEndpoint("2001:db8::1")),
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("2001:db8::1"),
+			Port:    "80",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name:    "missing second envoy service",
+		config:  multiServiceConfig,
+		objects: []runtime.Object{publicService, publicSliceOneAddr},
+		ing:     ing("name", "ns", withBasicSpec, withContour),
+		wantErr: fmt.Errorf("failed to get Service: service %q not found", publicNameB),
 	}, {
 		name:    "no public service",
 		objects: []runtime.Object{},
 		ing:     ing("name", "ns", withBasicSpec, withContour),
 		wantErr: fmt.Errorf("failed to get Service: service %q not found", publicName),
 	}, {
-		name:    "no public endpoints",
+		name:    "no public endpoint slices",
 		objects: []runtime.Object{publicService},
 		ing:     ing("name", "ns", withBasicSpec, withContour),
-		wantErr: fmt.Errorf("failed to get Endpoints: endpoints %q not found", publicName),
+		wantErr: fmt.Errorf("%w: no EndpointSlices found for %s/%s", errEndpointsNotReady, publicNS, publicName),
 	}, {
 		name:    "no port 80 in service",
-		objects: []runtime.Object{publicServiceNoPort80, publicEndpointsOneAddr},
+		objects: []runtime.Object{publicServiceNoPort80, publicSliceOneAddr},
 		ing:     ing("name", "ns", withBasicSpec, withContour),
 		wantErr: fmt.Errorf("failed to lookup port 80 in %s/%s: no port with number 80 found",
 			publicNS, publicName),
 	}, {
-		name:    "no matching port name in endpoints",
-		objects: []runtime.Object{publicService, publicEndpointsWrongPortName},
+		name:   "configured ports",
+		config: probePortsConfig,
+		objects: []runtime.Object{
+			publicListenerService,
+			privateService,
+			publicSliceOneAddr,
+			privateSliceNoAddr,
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("1.2.3.4"),
+			Port:    "8080",
+			PodPort: "1234",
+			URLs: []*url.URL{{
+				Scheme: "http",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name:   "configured ports by name (https redirected)",
+		config: probePortsConfig,
+		objects: []runtime.Object{
+			publicListenerService,
+			privateService,
+			endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "secure", 8443, readyEndpoint("1.2.3.4")),
+			privateSliceNoAddr,
+		},
+		ing: ing("name", "ns", withBasicSpec, withContour, withHTTPRedirected),
+		want: []status.ProbeTarget{{
+			PodIPs:  sets.New("1.2.3.4"),
+			Port:    "8443",
+			PodPort: "8443",
+			URLs: []*url.URL{{
+				Scheme: "https",
+				Host:   "example.com",
+			}},
+		}},
+	}, {
+		name:    "no configured port name in service",
+		config:  probePortsConfig,
+		objects: []runtime.Object{publicService, publicSliceOneAddr},
+		ing:     ing("name", "ns", withBasicSpec, withContour, withHTTPRedirected),
+		wantErr: fmt.Errorf(`failed to lookup port secure in %s/%s: no port with name "secure" found`,
+			publicNS, publicName),
+	}, {
+		name:    "no matching port name in endpoint slices",
+		objects: []runtime.Object{publicService, publicSliceWrongPortName},
 		ing:     ing("name", "ns", withBasicSpec, withContour),
-		wantErr: fmt.Errorf(`failed to lookup port name "asdf" in endpoints subset for %s/%s: no port for name "asdf" found`, publicNS, publicName),
+		wantErr: fmt.Errorf(`failed to lookup port name "asdf" in EndpointSlices for %s/%s: no port for name "asdf" found in EndpointSlice %s-a`, publicNS, publicName, publicName),
 	}}
 
 	for _, test := range tests {
@@ -130,11 +347,14 @@ func TestListProbeTargets(t *testing.T) {
 			tl := NewListers(test.objects)
 
 			l := &lister{
-				ServiceLister:   tl.GetK8sServiceLister(),
-				EndpointsLister: tl.GetEndpointsLister(),
+				ServiceLister:       tl.GetK8sServiceLister(),
+				EndpointSliceLister: tl.GetEndpointSliceLister(),
 			}
 
 			cfg := defaultConfig.DeepCopy()
+			if test.config != nil {
+				cfg = test.config.DeepCopy()
+			}
 			ctx := (&testConfigStore{config: cfg}).ToContext(context.Background())
 
 			got, gotErr := l.ListProbeTargets(ctx, test.ing)
@@ -142,6 +362,8 @@ func TestListProbeTargets(t *testing.T) {
 				t.Fatalf("ListProbeTargets() = %v, wanted %v", gotErr, test.wantErr)
 			} else if gotErr != nil && test.wantErr != nil && gotErr.Error() != test.wantErr.Error() {
 				t.Fatalf("ListProbeTargets() = %v, wanted %v", gotErr, test.wantErr)
+			} else if errors.Is(gotErr, errEndpointsNotReady) != errors.Is(test.wantErr, errEndpointsNotReady) {
+				t.Fatalf("ListProbeTargets() = %v, wanted %v", gotErr, test.wantErr)
 			}
 
 			if !cmp.Equal(test.want, got) {
@@ -151,7 +373,56 @@ func TestListProbeTargets(t *testing.T) {
 	}
 }
 
+const publicNameB = "envoy-stuff-b"
+
 var (
+	multiServiceConfig = &config.Config{
+		Contour: &config.Contour{
+			VisibilityKeys: map[v1alpha1.IngressVisibility]sets.Set[string]{
+				v1alpha1.IngressVisibilityClusterLocal: sets.New(privateKey),
+				v1alpha1.IngressVisibilityExternalIP:   sets.New(publicKey, publicNS+"/"+publicNameB),
+			},
+		},
+	}
+	probePortsConfig = &config.Config{
+		Contour: &config.Contour{
+			VisibilityKeys: defaultConfig.Contour.VisibilityKeys,
+			VisibilityProbePorts: map[v1alpha1.IngressVisibility]config.ProbePorts{
+				v1alpha1.IngressVisibilityExternalIP: {
+					HTTP:  intstr.FromInt(8080),
+					HTTPS: intstr.FromString("secure"),
+				},
+			},
+		},
+	}
+	publicListenerService = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: publicNS,
+			Name:      publicName,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{
+				Name: "asdf",
+				Port: 8080,
+			}, {
+				Name: "secure",
+				Port: 8443,
+			}},
+		},
+	}
+	publicServiceB = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: publicNS,
+			Name:      publicNameB,
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{
+				Name: "asdf",
+				Port: 80,
+			}},
+		},
+	}
+
 	publicService = &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: publicNS,
@@ -200,71 +471,45 @@ var (
 			}},
 		},
 	}
-	publicEndpointsOneAddr = &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: publicNS,
-			Name:      publicName,
-		},
-		Subsets: []corev1.EndpointSubset{{
-			Ports: []corev1.EndpointPort{{
-				Name: "asdf",
-				Port: 1234,
-			}},
-			Addresses: []corev1.EndpointAddress{{
-				IP: "1.2.3.4",
-			}},
-		}},
-	}
-	publicEndpointsWrongPortName = &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: publicNS,
-			Name:      publicName,
-		},
-		Subsets: []corev1.EndpointSubset{{
-			Ports: []corev1.EndpointPort{{
-				Name: "wrong",
-				Port: 1234,
-			}},
-			Addresses: []corev1.EndpointAddress{{
-				IP: "1.2.3.4",
-			}},
-		}},
+	publicSliceB             = endpointSlice(publicNS, publicNameB, "a", discoveryv1.AddressTypeIPv4, "asdf", 5678, readyEndpoint("5.6.7.8"))
+	publicSliceOneAddr       = endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234, readyEndpoint("1.2.3.4"))
+	publicSliceWrongPortName = endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "wrong", 1234, readyEndpoint("1.2.3.4"))
+	publicSlicesMultiPort    = []runtime.Object{
+		endpointSlice(publicNS, publicName, "a", discoveryv1.AddressTypeIPv4, "asdf", 1234, readyEndpoint("2.3.4.5")),
+		endpointSlice(publicNS, publicName, "b", discoveryv1.AddressTypeIPv4, "asdf", 4321, readyEndpoint("3.4.5.6"), readyEndpoint("4.3.2.1")),
 	}
-	publicEndpointsMultiAddrMultiSubset = &corev1.Endpoints{
+	privateSliceNoAddr = endpointSlice(privateNS, privateName, "a", discoveryv1.AddressTypeIPv4, "fdsa", 32)
+)
+
+func endpointSlice(namespace, service, suffix string, addressType discoveryv1.AddressType, portName string, port int32, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+	return &discoveryv1.EndpointSlice{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: publicNS,
-			Name:      publicName,
+			Namespace: namespace,
+			Name:      service + "-" + suffix,
+			Labels: map[string]string{
+				discoveryv1.LabelServiceName: service,
+			},
 		},
-		Subsets: []corev1.EndpointSubset{{
-			Ports: []corev1.EndpointPort{{
-				Name: "asdf",
-				Port: 1234,
-			}},
-			Addresses: []corev1.EndpointAddress{{
-				IP: "2.3.4.5",
-			}},
-		}, {
-			Ports: []corev1.EndpointPort{{
-				Name: "asdf",
-				Port: 4321,
-			}},
-			Addresses: []corev1.EndpointAddress{{
-				IP: "3.4.5.6",
-			}, {
-				IP: "4.3.2.1",
-			}},
+		AddressType: addressType,
+		Ports: []discoveryv1.EndpointPort{{
+			Name: ptr.String(portName),
+			Port: ptr.Int32(port),
 		}},
+		Endpoints: endpoints,
 	}
-	privateEndpointsNoAddr = &corev1.Endpoints{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: privateNS,
-			Name:      privateName,
+}
+
+func withIPFamilies(svc *corev1.Service, families ...corev1.IPFamily) *corev1.Service {
+	svc = svc.DeepCopy()
+	svc.Spec.IPFamilies = families
+	return svc
+}
+
+func readyEndpoint(addresses ...string) discoveryv1.Endpoint {
+	return discoveryv1.Endpoint{
+		Addresses: addresses,
+		Conditions: discoveryv1.EndpointConditions{
+			Ready: ptr.Bool(true),
 		},
-		Subsets: []corev1.EndpointSubset{{
-			Ports: []corev1.EndpointPort{{
-				Name: "fdsa",
-				Port: 32,
-			}},
-		}},
 	}
-)
+}
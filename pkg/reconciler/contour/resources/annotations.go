@@ -0,0 +1,273 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// ValidateAnnotations checks the annotations of the ingress that configure its
+// HTTPProxy resources, which otherwise fall back on their defaults.
+func ValidateAnnotations(ctx context.Context, ing *v1alpha1.Ingress) error {
+	cfg := config.FromContext(ctx)
+
+	if raw, ok := ing.Annotations[RetryPolicyKey]; ok {
+		if _, err := config.ParseRetryPolicy(raw); err != nil {
+			return fmt.Errorf("annotation %s is invalid: %w", RetryPolicyKey, err)
+		}
+	}
+	if raw, ok := ing.Annotations[LocalRateLimitKey]; ok {
+		if _, err := config.ParseLocalRateLimit(raw); err != nil {
+			return fmt.Errorf("annotation %s is invalid: %w", LocalRateLimitKey, err)
+		}
+	}
+	if raw, ok := ing.Annotations[GlobalRateLimitKey]; ok {
+		if _, err := config.ParseRateLimitDescriptors(raw); err != nil {
+			return fmt.Errorf("annotation %s is invalid: %w", GlobalRateLimitKey, err)
+		}
+	}
+	filter := ipFilter(&config.Contour{}, ing, "")
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("annotations %s, %s and %s are invalid: %w", IPAllowKey, IPDenyKey, IPFilterSourceKey, err)
+	}
+	if name, ok := ing.Annotations[JWTProviderKey]; ok {
+		if _, ok := cfg.Contour.JWTProviders[name]; !ok {
+			return fmt.Errorf("annotation %s names JWT provider %q, which is not in %s", JWTProviderKey, name, config.ContourConfigName)
+		}
+	}
+	if raw, ok := ing.Annotations[ExtensionServiceOptionsKey]; ok {
+		if _, ok := ing.Annotations[ExtensionServiceKey]; !ok {
+			return fmt.Errorf("annotation %s requires annotation %s", ExtensionServiceOptionsKey, ExtensionServiceKey)
+		}
+		if _, err := parseExtensionServiceOptions(raw); err != nil {
+			return fmt.Errorf("annotation %s is invalid: %w", ExtensionServiceOptionsKey, err)
+		}
+	}
+	if raw, ok := ing.Annotations[DisableDefaultAuthorizationKey]; ok {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return fmt.Errorf("annotation %s is invalid: %w", DisableDefaultAuthorizationKey, err)
+		}
+	}
+	if raw, ok := ing.Annotations[SkipClientCertValidationKey]; ok {
+		if _, err := strconv.ParseBool(raw); err != nil {
+			return fmt.Errorf("annotation %s is invalid: %w", SkipClientCertValidationKey, err)
+		}
+	}
+	if tls := downstreamTLS(cfg.Contour, ing, v1alpha1.IngressVisibilityExternalIP); tls != nil {
+		if err := tls.Validate(); err != nil {
+			return fmt.Errorf("downstream TLS annotations are invalid: %w", err)
+		}
+	}
+	for _, key := range []string{TimeoutResponseKey, TimeoutIdleKey, TimeoutIdleConnectionKey} {
+		if raw, ok := ing.Annotations[key]; ok {
+			if err := config.ValidateDuration(raw); err != nil {
+				return fmt.Errorf("annotation %s is invalid: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// extensionServiceOptions holds the options of the ExtensionServiceOptionsKey annotation.
+type extensionServiceOptions struct {
+	ResponseTimeout string                                `json:"responseTimeout,omitempty"`
+	FailOpen        bool                                  `json:"failOpen,omitempty"`
+	WithRequestBody *v1.AuthorizationServerBufferSettings `json:"withRequestBody,omitempty"`
+	Context         map[string]string                     `json:"context,omitempty"`
+	DisabledPaths   []string                              `json:"disabledPaths,omitempty"`
+}
+
+func parseExtensionServiceOptions(raw string) (*extensionServiceOptions, error) {
+	opts := &extensionServiceOptions{}
+	if err := yaml.UnmarshalStrict([]byte(raw), opts); err != nil {
+		return nil, err
+	}
+	if opts.ResponseTimeout != "" {
+		if err := config.ValidateDuration(opts.ResponseTimeout); err != nil {
+			return nil, fmt.Errorf("responseTimeout is invalid: %w", err)
+		}
+	}
+	for _, path := range opts.DisabledPaths {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("disabledPaths must start with /, got %q", path)
+		}
+	}
+	return opts, nil
+}
+
+// authorization returns the authorization server for the virtual hosts of the
+// ingress, along with the paths whose routes skip authorization, or nil when the
+// ingress has no ExtensionService.
+func authorization(ing *v1alpha1.Ingress) (*v1.AuthorizationServer, sets.Set[string]) {
+	extensionService, ok := ing.Annotations[ExtensionServiceKey]
+	if !ok {
+		return nil, nil
+	}
+	auth := &v1.AuthorizationServer{
+		ExtensionServiceRef: v1.ExtensionServiceReference{
+			Name:      extensionService,
+			Namespace: ing.Annotations[ExtensionServiceNamespaceKey],
+		},
+	}
+
+	raw, ok := ing.Annotations[ExtensionServiceOptionsKey]
+	if !ok {
+		return auth, nil
+	}
+	opts, err := parseExtensionServiceOptions(raw)
+	if err != nil {
+		// Invalid annotations are surfaced on the KIngress by ValidateAnnotations.
+		return auth, nil
+	}
+	auth.ResponseTimeout = opts.ResponseTimeout
+	auth.FailOpen = opts.FailOpen
+	auth.WithRequestBody = opts.WithRequestBody
+	if len(opts.Context) != 0 {
+		auth.AuthPolicy = &v1.AuthorizationPolicy{Context: opts.Context}
+	}
+	return auth, sets.New(opts.DisabledPaths...)
+}
+
+// defaultAuthorization returns the default-authorization from config-contour for
+// the virtual hosts of the ingress with the given visibility, or nil when it does
+// not apply: it only applies to the external virtual hosts of ingresses that have
+// neither their own ExtensionService nor opted out, and never to endpoint probes.
+func defaultAuthorization(cfg *config.Contour, ing *v1alpha1.Ingress, vis v1alpha1.IngressVisibility) *v1.AuthorizationServer {
+	if cfg.DefaultAuthorization == nil || vis != v1alpha1.IngressVisibilityExternalIP {
+		return nil
+	}
+	if _, ok := ing.Annotations[ExtensionServiceKey]; ok {
+		return nil
+	}
+	if _, ok := ing.Annotations[EndpointsProbeKey]; ok {
+		return nil
+	}
+	if disabled, _ := strconv.ParseBool(ing.Annotations[DisableDefaultAuthorizationKey]); disabled {
+		return nil
+	}
+	return cfg.DefaultAuthorization.DeepCopy()
+}
+
+// jwtProviders returns the JWT providers for the virtual hosts of the ingress,
+// with the one selected by its annotation as the default for every route.
+func jwtProviders(cfg *config.Contour, ing *v1alpha1.Ingress) []v1.JWTProvider {
+	name, ok := ing.Annotations[JWTProviderKey]
+	if !ok {
+		return nil
+	}
+	provider, ok := cfg.JWTProviders[name]
+	if !ok {
+		// Unknown providers are surfaced on the KIngress by ValidateAnnotations.
+		return nil
+	}
+	provider = *provider.DeepCopy()
+	provider.Default = true
+	return []v1.JWTProvider{provider}
+}
+
+// ipFilter returns the IP filter for the routes of the ingress with the given
+// visibility: the lists from its annotations replace those from config-contour.
+func ipFilter(cfg *config.Contour, ing *v1alpha1.Ingress, vis v1alpha1.IngressVisibility) *config.IPFilter {
+	defaults := cfg.IPFilters[vis]
+	filter := defaults.DeepCopy()
+	allow, hasAllow := ing.Annotations[IPAllowKey]
+	deny, hasDeny := ing.Annotations[IPDenyKey]
+	if hasAllow || hasDeny {
+		filter.Allow = splitList(allow)
+		filter.Deny = splitList(deny)
+	}
+	if source, ok := ing.Annotations[IPFilterSourceKey]; ok {
+		filter.Source = v1.IPFilterSource(strings.TrimSpace(source))
+	}
+	return filter
+}
+
+// downstreamTLS returns the TLS settings of the virtual hosts of the ingress with
+// the given visibility, or nil when they have none: the annotations of the ingress
+// override the downstream-tls from config-contour, field by field.
+func downstreamTLS(cfg *config.Contour, ing *v1alpha1.Ingress, vis v1alpha1.IngressVisibility) *config.DownstreamTLS {
+	if vis != v1alpha1.IngressVisibilityExternalIP {
+		return nil
+	}
+	tls := cfg.DownstreamTLS.DeepCopy()
+	if tls == nil {
+		tls = &config.DownstreamTLS{}
+	}
+	if v, ok := ing.Annotations[TLSMinimumProtocolVersionKey]; ok {
+		tls.MinimumProtocolVersion = strings.TrimSpace(v)
+	}
+	if v, ok := ing.Annotations[TLSMaximumProtocolVersionKey]; ok {
+		tls.MaximumProtocolVersion = strings.TrimSpace(v)
+	}
+
+	clientValidation := func() *v1.DownstreamValidation {
+		if tls.ClientValidation == nil {
+			tls.ClientValidation = &v1.DownstreamValidation{}
+		}
+		return tls.ClientValidation
+	}
+	if v, ok := ing.Annotations[ClientCASecretKey]; ok {
+		clientValidation().CACertificate = strings.TrimSpace(v)
+	}
+	if v, ok := ing.Annotations[ClientCRLSecretKey]; ok {
+		clientValidation().CertificateRevocationList = strings.TrimSpace(v)
+	}
+	if v, ok := ing.Annotations[SkipClientCertValidationKey]; ok {
+		// Invalid annotations are surfaced on the KIngress by ValidateAnnotations.
+		skip, _ := strconv.ParseBool(v)
+		clientValidation().SkipClientCertValidation = skip
+	}
+
+	if *tls == (config.DownstreamTLS{}) {
+		return nil
+	}
+	return tls
+}
+
+// RequiresClientCertificate returns whether the external virtual hosts of the
+// ingress reject clients without a certificate, such as our status prober.
+func RequiresClientCertificate(ctx context.Context, ing *v1alpha1.Ingress) bool {
+	tls := downstreamTLS(config.FromContext(ctx).Contour, ing, v1alpha1.IngressVisibilityExternalIP)
+	return tls != nil && tls.Validate() == nil && tls.RequiresClientCertificate()
+}
+
+func splitList(raw string) (list []string) {
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// timeoutAnnotation returns the value of the given timeout annotation on the
+// ingress, or def when it is missing or invalid.
+func timeoutAnnotation(ing *v1alpha1.Ingress, key, def string) string {
+	if raw, ok := ing.Annotations[key]; ok && config.ValidateDuration(raw) == nil {
+		return raw
+	}
+	return def
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     bool
+	}{{
+		name: "no annotations",
+	}, {
+		name: "valid retry policy",
+		annotations: map[string]string{
+			RetryPolicyKey: "{count: 1, perTryTimeout: 1s, retryOn: [5xx]}",
+		},
+	}, {
+		name: "disabled retries",
+		annotations: map[string]string{
+			RetryPolicyKey: "count: -1",
+		},
+	}, {
+		name: "malformed retry policy",
+		annotations: map[string]string{
+			RetryPolicyKey: "count: [",
+		},
+		wantErr: true,
+	}, {
+		name: "unknown retry condition",
+		annotations: map[string]string{
+			RetryPolicyKey: "retryOn: [sometimes]",
+		},
+		wantErr: true,
+	}, {
+		name: "valid local rate limit",
+		annotations: map[string]string{
+			LocalRateLimitKey: "{requests: 10, unit: second}",
+		},
+	}, {
+		name: "invalid local rate limit",
+		annotations: map[string]string{
+			LocalRateLimitKey: "{requests: 10, unit: fortnight}",
+		},
+		wantErr: true,
+	}, {
+		name: "valid global rate limit",
+		annotations: map[string]string{
+			GlobalRateLimitKey: "- entries: [{remoteAddress: {}}]",
+		},
+	}, {
+		name: "malformed global rate limit",
+		annotations: map[string]string{
+			GlobalRateLimitKey: "entries: [{remoteAddress: {}}]",
+		},
+		wantErr: true,
+	}, {
+		name: "valid ip allow list",
+		annotations: map[string]string{
+			IPAllowKey:        "10.0.0.0/8, 192.168.1.1,fd00::/8",
+			IPFilterSourceKey: "Peer",
+		},
+	}, {
+		name: "invalid ip deny list",
+		annotations: map[string]string{
+			IPDenyKey: "10.0.0.0/8,not-an-ip",
+		},
+		wantErr: true,
+	}, {
+		name: "ip allow and deny lists",
+		annotations: map[string]string{
+			IPAllowKey: "10.0.0.0/8",
+			IPDenyKey:  "192.168.0.0/16",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid ip filter source",
+		annotations: map[string]string{
+			IPFilterSourceKey: "Client",
+		},
+		wantErr: true,
+	}, {
+		name: "known jwt provider",
+		annotations: map[string]string{
+			JWTProviderKey: "corp",
+		},
+	}, {
+		name: "unknown jwt provider",
+		annotations: map[string]string{
+			JWTProviderKey: "partner",
+		},
+		wantErr: true,
+	}, {
+		name: "valid extension service options",
+		annotations: map[string]string{
+			ExtensionServiceKey: "es",
+			ExtensionServiceOptionsKey: `
+responseTimeout: 500ms
+failOpen: true
+withRequestBody: {maxRequestBytes: 1024}
+context: {tenant: a}
+disabledPaths: [/healthz]`,
+		},
+	}, {
+		name: "extension service options without extension service",
+		annotations: map[string]string{
+			ExtensionServiceOptionsKey: "failOpen: true",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid extension service response timeout",
+		annotations: map[string]string{
+			ExtensionServiceKey:        "es",
+			ExtensionServiceOptionsKey: "responseTimeout: soon",
+		},
+		wantErr: true,
+	}, {
+		name: "unknown extension service option",
+		annotations: map[string]string{
+			ExtensionServiceKey:        "es",
+			ExtensionServiceOptionsKey: "failClosed: true",
+		},
+		wantErr: true,
+	}, {
+		name: "relative extension service disabled path",
+		annotations: map[string]string{
+			ExtensionServiceKey:        "es",
+			ExtensionServiceOptionsKey: "disabledPaths: [healthz]",
+		},
+		wantErr: true,
+	}, {
+		name: "default authorization opt-out",
+		annotations: map[string]string{
+			DisableDefaultAuthorizationKey: "true",
+		},
+	}, {
+		name: "invalid default authorization opt-out",
+		annotations: map[string]string{
+			DisableDefaultAuthorizationKey: "yes please",
+		},
+		wantErr: true,
+	}, {
+		name: "valid downstream TLS",
+		annotations: map[string]string{
+			ClientCASecretKey:            "client-ca",
+			ClientCRLSecretKey:           "partners/crl",
+			TLSMinimumProtocolVersionKey: "1.3",
+		},
+	}, {
+		name: "client certificates requested without validation",
+		annotations: map[string]string{
+			SkipClientCertValidationKey: "true",
+		},
+	}, {
+		name: "invalid skip client certificate validation",
+		annotations: map[string]string{
+			SkipClientCertValidationKey: "sometimes",
+		},
+		wantErr: true,
+	}, {
+		name: "client CRL without CA",
+		annotations: map[string]string{
+			ClientCRLSecretKey: "partners/crl",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid TLS protocol version",
+		annotations: map[string]string{
+			TLSMaximumProtocolVersionKey: "1.1",
+		},
+		wantErr: true,
+	}, {
+		name: "valid timeouts",
+		annotations: map[string]string{
+			TimeoutResponseKey:       "infinity",
+			TimeoutIdleKey:           "5m",
+			TimeoutIdleConnectionKey: "90s",
+		},
+	}, {
+		name: "infinite timeout",
+		annotations: map[string]string{
+			TimeoutIdleKey: "infinite",
+		},
+	}, {
+		name: "unitless zero timeout",
+		annotations: map[string]string{
+			TimeoutResponseKey: "0",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid response timeout",
+		annotations: map[string]string{
+			TimeoutResponseKey: "forever",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid idle timeout",
+		annotations: map[string]string{
+			TimeoutIdleKey: "10",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid idle connection timeout",
+		annotations: map[string]string{
+			TimeoutIdleConnectionKey: "",
+		},
+		wantErr: true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := &v1alpha1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "foo",
+					Name:        "bar",
+					Annotations: tc.annotations,
+				},
+			}
+			ctx := (&testConfigStore{config: &config.Config{
+				Contour: &config.Contour{
+					JWTProviders: map[string]v1.JWTProvider{
+						"corp": {
+							Name:       "corp",
+							RemoteJWKS: v1.RemoteJWKS{URI: "https://jwks.example.com/keys"},
+						},
+					},
+				},
+			}}).ToContext(context.Background())
+			if err := ValidateAnnotations(ctx, ing); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateAnnotations() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -25,6 +25,10 @@ const (
 	// ParentKey hold the name of the parent KIngress resource, since OwnerReferences cannot
 	// be used in filter expressions.
 	ParentKey = "contour.networking.knative.dev/parent"
+	// ParentNamespaceKey holds the namespace of the parent KIngress resource on the root
+	// HTTPProxy resources that live in the root-proxy-namespace, which cannot have an
+	// OwnerReference to a KIngress in another namespace.
+	ParentNamespaceKey = "contour.networking.knative.dev/parentNamespace"
 	// DomainHashKey contains the hash of the fqdn for which this HTTPProxy exists.  We use
 	// the hash in place of the actual fqdn because there is a limit on the length of label
 	// values.
@@ -37,6 +41,10 @@ const (
 	// EndpointsProbeKey is placed on child Ingress resources to bypass Endpoint probing,
 	// since the child ingress exists to be said endpoint probe.
 	EndpointsProbeKey = "contour.networking.knative.dev/endpointsProbe"
+
+	// BackendServiceKey is placed on the ExternalName Services bridging to backends in
+	// other namespaces, and holds the namespace/name of the Service they resolve to.
+	BackendServiceKey = "contour.networking.knative.dev/backendService"
 )
 
 const (
@@ -57,4 +65,55 @@ const (
 	// generated HttpProxy
 	ExtensionServiceKey          = "contour.networking.knative.dev/extension-service"
 	ExtensionServiceNamespaceKey = "contour.networking.knative.dev/extension-service-namespace"
+	// ExtensionServiceOptionsKey holds YAML with the further options of the authorization
+	// by the ExtensionService: responseTimeout, failOpen, withRequestBody, context and
+	// disabledPaths, the paths of the ingress that skip authorization.
+	ExtensionServiceOptionsKey = "contour.networking.knative.dev/extension-service-options"
+	// DisableDefaultAuthorizationKey set to "true" opts the ingress out of the
+	// default-authorization from config-contour.
+	DisableDefaultAuthorizationKey = "contour.networking.knative.dev/disable-default-authorization"
+
+	// RetryPolicyKey holds a Contour retry policy in YAML, which replaces the
+	// retry-policy from config-contour on the routes of the ingress.
+	RetryPolicyKey = "contour.networking.knative.dev/retry-policy"
+
+	// These annotations override the route timeouts from config-contour. They hold
+	// "infinity" or a duration such as "30s".
+	TimeoutResponseKey       = "contour.networking.knative.dev/timeout-response"
+	TimeoutIdleKey           = "contour.networking.knative.dev/timeout-idle"
+	TimeoutIdleConnectionKey = "contour.networking.knative.dev/timeout-idle-connection"
+
+	// LocalRateLimitKey holds a Contour local rate limit policy in YAML, which replaces
+	// the local-rate-limit from config-contour on the routes of the ingress.
+	LocalRateLimitKey = "contour.networking.knative.dev/local-rate-limit"
+
+	// GlobalRateLimitKey holds a YAML list of Contour global rate limit descriptors for
+	// the virtual hosts of the ingress, which replace global-rate-limit-descriptors from
+	// config-contour.
+	GlobalRateLimitKey = "contour.networking.knative.dev/global-rate-limit"
+
+	// IPAllowKey and IPDenyKey hold comma-separated CIDRs that replace the
+	// ip-filter-policy from config-contour on the routes of the ingress. At most one
+	// of them may be set. IPFilterSourceKey selects the address filtered on, which
+	// is either Peer or Remote.
+	IPAllowKey        = "contour.networking.knative.dev/ip-allow"
+	IPDenyKey         = "contour.networking.knative.dev/ip-deny"
+	IPFilterSourceKey = "contour.networking.knative.dev/ip-filter-source"
+
+	// JWTProviderKey names the provider from the jwt-providers of config-contour whose
+	// JWTs are required on the routes of the ingress.
+	JWTProviderKey = "contour.networking.knative.dev/jwt-provider"
+
+	// These annotations override the downstream-tls from config-contour on the
+	// external virtual hosts of the ingress that terminate TLS. The secrets are
+	// given as "name" in the namespace of the ingress or "namespace/name".
+	// ClientCASecretKey requires clients to present a certificate signed by the CA
+	// in the secret, ClientCRLSecretKey checks it against the revocation lists in
+	// the secret, and SkipClientCertValidationKey set to "true" requests client
+	// certificates without verifying them. The protocol versions are 1.2 or 1.3.
+	ClientCASecretKey            = "contour.networking.knative.dev/client-ca-secret"
+	ClientCRLSecretKey           = "contour.networking.knative.dev/client-crl-secret"
+	SkipClientCertValidationKey  = "contour.networking.knative.dev/skip-client-cert-validation"
+	TLSMinimumProtocolVersionKey = "contour.networking.knative.dev/tls-minimum-protocol-version"
+	TLSMaximumProtocolVersionKey = "contour.networking.knative.dev/tls-maximum-protocol-version"
 )
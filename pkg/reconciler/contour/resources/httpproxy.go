@@ -29,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources/names"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/networking/pkg/certificates"
 	netcfg "knative.dev/networking/pkg/config"
@@ -41,14 +42,20 @@ import (
 )
 
 type ServiceInfo struct {
+	// Namespace and Name identify the Service, which may live outside of
+	// the KIngress's namespace.
+	Namespace       string
+	Name            string
 	Port            intstr.IntOrString
 	RawVisibilities sets.Set[string]
 	// If the Host header sent to this service needs to be rewritten,
 	// then track that so we can send it for probing.
 	RewriteHost string
 
-	// TODO(https://github.com/knative-sandbox/net-certmanager/issues/44): Remove this.
-	HasPath bool
+	// HTTPChallenge is set when the service is only reached under the ACME
+	// HTTPChallengePath. Such solvers don't answer our probes, so they are
+	// not covered by the endpoints probe.
+	HTTPChallenge bool
 }
 
 func (si *ServiceInfo) Visibilities() (vis []v1alpha1.IngressVisibility) {
@@ -58,22 +65,44 @@ func (si *ServiceInfo) Visibilities() (vis []v1alpha1.IngressVisibility) {
 	return vis
 }
 
+// ServiceKey returns the key under which ServiceNames tracks the given backend:
+// the bare Service name for Services in the KIngress's namespace, and
+// namespace/name for Services elsewhere.
+func ServiceKey(ing *v1alpha1.Ingress, backend v1alpha1.IngressBackend) string {
+	if ns := backendNamespace(ing, backend); ns != ing.Namespace {
+		return ns + "/" + backend.ServiceName
+	}
+	return backend.ServiceName
+}
+
+func backendNamespace(ing *v1alpha1.Ingress, backend v1alpha1.IngressBackend) string {
+	if backend.ServiceNamespace == "" {
+		return ing.Namespace
+	}
+	return backend.ServiceNamespace
+}
+
 func ServiceNames(ctx context.Context, ing *v1alpha1.Ingress) map[string]ServiceInfo {
 	s := map[string]ServiceInfo{}
 	for _, rule := range ing.Spec.Rules {
 		for _, path := range rule.HTTP.Paths {
 			for _, split := range path.Splits {
-				si, ok := s[split.ServiceName]
+				key := ServiceKey(ing, split.IngressBackend)
+				si, ok := s[key]
 				if !ok {
 					si = ServiceInfo{
+						Namespace:       backendNamespace(ing, split.IngressBackend),
+						Name:            split.ServiceName,
 						Port:            split.ServicePort,
 						RawVisibilities: sets.New[string](),
-						HasPath:         path.Path != "",
+						HTTPChallenge:   strings.Contains(path.Path, HTTPChallengePath),
 						RewriteHost:     path.RewriteHost,
 					}
+				} else if !strings.Contains(path.Path, HTTPChallengePath) {
+					si.HTTPChallenge = false
 				}
 				si.RawVisibilities.Insert(string(rule.Visibility))
-				s[split.ServiceName] = si
+				s[key] = si
 			}
 		}
 	}
@@ -97,6 +126,69 @@ func defaultRetryPolicy() *v1.RetryPolicy {
 	}
 }
 
+// retryPolicy returns the retry policy for the routes of the ingress: the
+// one from its annotation, else the one from config-contour, else the default.
+func retryPolicy(cfg *config.Contour, ing *v1alpha1.Ingress) *v1.RetryPolicy {
+	if raw, ok := ing.Annotations[RetryPolicyKey]; ok {
+		// Invalid annotations are surfaced on the KIngress by ValidateAnnotations.
+		if policy, err := config.ParseRetryPolicy(raw); err == nil {
+			return policy
+		}
+	}
+	if cfg.RetryPolicy != nil {
+		return cfg.RetryPolicy.DeepCopy()
+	}
+	// By default retry on connection problems twice.
+	// This matches the default behavior of Istio:
+	// https://istio.io/latest/docs/concepts/traffic-management/#retries
+	// However, in addition to the codes specified by istio
+	return defaultRetryPolicy()
+}
+
+// rateLimitPolicy returns the rate limit policy for the routes of the ingress
+// with the given visibility, or nil when they are not rate limited.
+func rateLimitPolicy(cfg *config.Contour, ing *v1alpha1.Ingress, vis v1alpha1.IngressVisibility) *v1.RateLimitPolicy {
+	if !cfg.LocalRateLimitVisibilities.Has(vis) {
+		return nil
+	}
+	local := cfg.LocalRateLimit
+	if raw, ok := ing.Annotations[LocalRateLimitKey]; ok {
+		// Invalid annotations are surfaced on the KIngress by ValidateAnnotations.
+		if policy, err := config.ParseLocalRateLimit(raw); err == nil {
+			local = policy
+		}
+	}
+	if local == nil {
+		return nil
+	}
+	return &v1.RateLimitPolicy{Local: local.DeepCopy()}
+}
+
+// globalRateLimitPolicy returns the global rate limit policy for the virtual
+// hosts of the ingress with the given visibility, or nil when they are not
+// rate limited.  The descriptors from the annotation apply to every visibility,
+// those from config-contour only to external virtual hosts.
+func globalRateLimitPolicy(cfg *config.Contour, ing *v1alpha1.Ingress, vis v1alpha1.IngressVisibility) *v1.RateLimitPolicy {
+	var descriptors []v1.RateLimitDescriptor
+	if vis == v1alpha1.IngressVisibilityExternalIP {
+		descriptors = cfg.GlobalRateLimitDescriptors
+	}
+	if raw, ok := ing.Annotations[GlobalRateLimitKey]; ok {
+		// Invalid annotations are surfaced on the KIngress by ValidateAnnotations.
+		if parsed, err := config.ParseRateLimitDescriptors(raw); err == nil {
+			descriptors = parsed
+		}
+	}
+	if len(descriptors) == 0 {
+		return nil
+	}
+	global := &v1.GlobalRateLimitPolicy{Descriptors: make([]v1.RateLimitDescriptor, len(descriptors))}
+	for i := range descriptors {
+		descriptors[i].DeepCopyInto(&global.Descriptors[i])
+	}
+	return &v1.RateLimitPolicy{Global: global}
+}
+
 func addHostEntries(entries map[string]v1alpha1.IngressTLS, list []v1alpha1.IngressTLS) {
 	for _, tls := range list {
 		for _, host := range tls.Hosts {
@@ -136,19 +228,25 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 	proxies := []*v1.HTTPProxy{}
 	for _, rule := range ing.Spec.Rules {
 		class := cfg.Contour.VisibilityClasses[rule.Visibility]
+		vhostRateLimit := globalRateLimitPolicy(cfg.Contour, ing, rule.Visibility)
+		vhostJWTProviders := jwtProviders(cfg.Contour, ing)
+		vhostAuth, authDisabledPaths := authorization(ing)
+		vhostDefaultAuth := defaultAuthorization(cfg.Contour, ing, rule.Visibility)
+		vhostTLS := downstreamTLS(cfg.Contour, ing, rule.Visibility)
+		if vhostTLS != nil && vhostTLS.Validate() != nil {
+			// Invalid annotations are surfaced on the KIngress by ValidateAnnotations.
+			vhostTLS = nil
+		}
 
 		routes := make([]v1.Route, 0, len(rule.HTTP.Paths))
 		for _, path := range rule.HTTP.Paths {
 			top := &v1.TimeoutPolicy{
-				Response: cfg.Contour.TimeoutPolicyResponse,
-				Idle:     cfg.Contour.TimeoutPolicyIdle,
+				Response:       timeoutAnnotation(ing, TimeoutResponseKey, cfg.Contour.TimeoutPolicyResponse),
+				Idle:           timeoutAnnotation(ing, TimeoutIdleKey, cfg.Contour.TimeoutPolicyIdle),
+				IdleConnection: timeoutAnnotation(ing, TimeoutIdleConnectionKey, ""),
 			}
 
-			// By default retry on connection problems twice.
-			// This matches the default behavior of Istio:
-			// https://istio.io/latest/docs/concepts/traffic-management/#retries
-			// However, in addition to the codes specified by istio
-			retry := defaultRetryPolicy()
+			retry := retryPolicy(cfg.Contour, ing)
 
 			preSplitHeaders := &v1.HeadersPolicy{
 				Set: make([]v1.HeaderValue, 0, len(path.AppendHeaders)),
@@ -180,6 +278,13 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 					Weight: int64(split.Percent),
 				}
 
+				// Contour only routes to Services in the HTTPProxy's namespace,
+				// so backends elsewhere are reached through a bridge Service.
+				svcNamespace := backendNamespace(ing, split.IngressBackend)
+				if svcNamespace != ing.Namespace {
+					svc.Name = names.BackendBridge(ing, svcNamespace, split.ServiceName)
+				}
+
 				postSplitHeaders := &v1.HeadersPolicy{
 					Set: make([]v1.HeaderValue, 0, len(split.AppendHeaders)),
 				}
@@ -204,7 +309,7 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 
 				svc.RequestHeadersPolicy = postSplitHeaders
 
-				if proto, ok := serviceToProtocol[split.ServiceName]; ok {
+				if proto, ok := serviceToProtocol[ServiceKey(ing, split.IngressBackend)]; ok {
 					// In order for domain mappings to work with internal
 					// encryption, need to unencrypt traffic back to the envoy.
 					// See
@@ -222,9 +327,9 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 				if cfg.Network != nil && cfg.Network.SystemInternalTLSEnabled() {
 					svc.UpstreamValidation = &v1.UpstreamValidation{
 						CACertificate: fmt.Sprintf("%s/%s", system.Namespace(), netcfg.ServingRoutingCertName),
-						SubjectName:   certificates.DataPlaneUserSAN(ing.Namespace),
+						SubjectName:   certificates.DataPlaneUserSAN(svcNamespace),
 						SubjectNames: []string{
-							certificates.DataPlaneUserSAN(ing.Namespace),
+							certificates.DataPlaneUserSAN(svcNamespace),
 							certificates.DataPlaneRoutingSAN,
 						},
 					}
@@ -254,6 +359,30 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 				})
 			}
 
+			ai := allowInsecure
+			if rule.Visibility == v1alpha1.IngressVisibilityClusterLocal {
+				ai = true
+			}
+
+			_, isProbe := path.Headers[netheader.HashKey]
+			if isProbe {
+				// The probe route skips the rate limits, filters and authorization
+				// below, so only our prober's requests, which queue-proxy and the
+				// activator answer themselves, may take it.  Anyone may send the
+				// hash header alone.
+				conditions = append(conditions, v1.MatchCondition{
+					Header: &v1.HeaderMatchCondition{
+						Name:  netheader.ProbeKey,
+						Exact: netheader.ProbeValue,
+					},
+				})
+				if vhostTLS != nil && vhostTLS.RequiresClientCertificate() {
+					// Our prober has no client certificate, so it probes over
+					// plain HTTP instead.
+					ai = true
+				}
+			}
+
 			if len(conditions) > 1 {
 				sort.Slice(conditions, func(i, j int) bool {
 					hasPrefixLHS := conditions[i].Prefix != ""
@@ -267,24 +396,49 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 					return conditions[i].Header.Name > conditions[j].Header.Name
 				})
 			}
-			ai := allowInsecure
-			if rule.Visibility == v1alpha1.IngressVisibilityClusterLocal {
-				ai = true
+			// Neither our probes nor ACME challenges may be rate limited, filtered
+			// or require a JWT or authorization.
+			var rlp *v1.RateLimitPolicy
+			var ipAllow, ipDeny []v1.IPFilterPolicy
+			var jwt *v1.JWTVerificationPolicy
+			var authPolicy *v1.AuthorizationPolicy
+			if !isProbe && !strings.Contains(path.Path, HTTPChallengePath) {
+				rlp = rateLimitPolicy(cfg.Contour, ing, rule.Visibility)
+				ipAllow, ipDeny = ipFilter(cfg.Contour, ing, rule.Visibility).Policies()
+				if vhostAuth != nil && authDisabledPaths.Has(path.Path) {
+					authPolicy = &v1.AuthorizationPolicy{Disabled: true}
+				}
+			} else {
+				if vhostRateLimit != nil {
+					rlp = &v1.RateLimitPolicy{Global: &v1.GlobalRateLimitPolicy{Disabled: true}}
+				}
+				if len(vhostJWTProviders) != 0 {
+					jwt = &v1.JWTVerificationPolicy{Disabled: true}
+				}
+				if vhostAuth != nil || vhostDefaultAuth != nil {
+					authPolicy = &v1.AuthorizationPolicy{Disabled: true}
+				}
 			}
 
 			routes = append(routes, v1.Route{
-				Conditions:           conditions,
-				TimeoutPolicy:        top,
-				RetryPolicy:          retry,
-				Services:             svcs,
-				EnableWebsockets:     true,
-				RequestHeadersPolicy: preSplitHeaders,
-				PermitInsecure:       ai,
+				Conditions:            conditions,
+				TimeoutPolicy:         top,
+				RetryPolicy:           retry,
+				RateLimitPolicy:       rlp,
+				IPAllowFilterPolicy:   ipAllow,
+				IPDenyFilterPolicy:    ipDeny,
+				JWTVerificationPolicy: jwt,
+				AuthPolicy:            authPolicy,
+				Services:              svcs,
+				EnableWebsockets:      true,
+				RequestHeadersPolicy:  preSplitHeaders,
+				PermitInsecure:        ai,
 			})
 		}
 
 		// Propagate annotations from the parent KIngress to the HTTPProxy
-		// The Contour class annotation always takes precedence.
+		// The Contour class annotation always takes precedence, unless
+		// setClass drops it in favor of Spec.IngressClassName.
 		annotations := kmeta.UnionMaps(ing.GetAnnotations(), map[string]string{
 			ClassKey: class,
 		})
@@ -315,9 +469,8 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 				// Ideally these would just be marked ClusterLocal :(
 				if strings.HasSuffix(originalHost, network.GetClusterDomainName()) {
 					class = cfg.Contour.VisibilityClasses[v1alpha1.IngressVisibilityClusterLocal]
-					hostProxy.Annotations[ClassKey] = class
-					hostProxy.Labels[ClassKey] = class
 				}
+				setClass(hostProxy, cfg.Contour.IngressClassMode, class)
 
 				hostProxy.Name = kmeta.ChildName(ing.Name+"-"+class+"-", host)
 
@@ -329,18 +482,7 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 					hostProxy.Spec.VirtualHost.CORSPolicy = cfg.Contour.CORSPolicy
 				}
 
-				// Set ExtensionService if annotation is present
-				if extensionService, ok := ing.Annotations[ExtensionServiceKey]; ok {
-					hostProxy.Spec.VirtualHost.Authorization = &v1.AuthorizationServer{}
-					hostProxy.Spec.VirtualHost.Authorization.ExtensionServiceRef = v1.ExtensionServiceReference{
-						Name: extensionService,
-					}
-
-					if extensionServiceNamespace, ok := ing.Annotations[ExtensionServiceNamespaceKey]; ok {
-						hostProxy.Spec.VirtualHost.Authorization.ExtensionServiceRef.Namespace = extensionServiceNamespace
-					}
-				}
-
+				hostProxy.Spec.VirtualHost.RateLimitPolicy = vhostRateLimit.DeepCopy()
 				//nolint:gosec // No strong cryptography needed.
 				hostProxy.Labels[DomainHashKey] = fmt.Sprintf("%x", sha1.Sum([]byte(host)))
 
@@ -349,14 +491,120 @@ func MakeHTTPProxies(ctx context.Context, ing *v1alpha1.Ingress, serviceToProtoc
 					hostProxy.Spec.VirtualHost.TLS = &v1.TLS{
 						SecretName: fmt.Sprintf("%s/%s", tls.SecretNamespace, tls.SecretName),
 					}
-				} else if s := cfg.Contour.DefaultTLSSecret; s != nil && rule.Visibility == v1alpha1.IngressVisibilityExternalIP {
+				} else if s := cfg.Contour.DefaultTLSSecretFor(host); s != nil && rule.Visibility == v1alpha1.IngressVisibilityExternalIP {
 					hostProxy.Spec.VirtualHost.TLS = &v1.TLS{SecretName: s.String()}
 				}
+				if t := hostProxy.Spec.VirtualHost.TLS; t != nil && vhostTLS != nil {
+					t.MinimumProtocolVersion = vhostTLS.MinimumProtocolVersion
+					t.MaximumProtocolVersion = vhostTLS.MaximumProtocolVersion
+					t.ClientValidation = vhostTLS.ClientValidation.DeepCopy()
+				}
+
+				// Contour only verifies JWTs on TLS virtual hosts.
+				if hostProxy.Spec.VirtualHost.TLS != nil {
+					hostProxy.Spec.VirtualHost.JWTProviders = vhostJWTProviders
+				} else {
+					for i := range hostProxy.Spec.Routes {
+						hostProxy.Spec.Routes[i].JWTVerificationPolicy = nil
+					}
+				}
+
+				// Set ExtensionService if annotation is present, otherwise the
+				// default authorization, which Contour only accepts on TLS virtual hosts.
+				switch {
+				case vhostAuth != nil:
+					hostProxy.Spec.VirtualHost.Authorization = vhostAuth.DeepCopy()
+				case vhostDefaultAuth != nil && hostProxy.Spec.VirtualHost.TLS != nil:
+					hostProxy.Spec.VirtualHost.Authorization = vhostDefaultAuth.DeepCopy()
+				default:
+					for i := range hostProxy.Spec.Routes {
+						hostProxy.Spec.Routes[i].AuthPolicy = nil
+					}
+				}
 
-				proxies = append(proxies, hostProxy)
+				if cfg.Contour.RootProxyNamespace == "" {
+					proxies = append(proxies, hostProxy)
+					continue
+				}
+				proxies = append(proxies, hostProxy, rootProxy(cfg.Contour.RootProxyNamespace, ing, hostProxy))
+				hostProxy.Spec.VirtualHost = nil
 			}
 		}
 	}
 
 	return proxies
 }
+
+// rootProxy returns the root HTTPProxy that includes the routes of the given host
+// proxy from the root-proxy-namespace, and takes over its virtual host.  Its name
+// only depends on the host, so that a single KIngress at a time claims the host.
+func rootProxy(namespace string, ing *v1alpha1.Ingress, hostProxy *v1.HTTPProxy) *v1.HTTPProxy {
+	root := hostProxy.DeepCopy()
+	root.Namespace = namespace
+	var prefix string
+	if class := root.Labels[ClassKey]; class != "" {
+		prefix = class + "-"
+	}
+	root.Name = kmeta.ChildName(prefix, root.Spec.VirtualHost.Fqdn)
+	root.Labels[ParentNamespaceKey] = ing.Namespace
+	// The KIngress is in another namespace, so it cannot own the root proxy.
+	root.OwnerReferences = nil
+	root.Spec.Routes = nil
+	// The host proxy's routes carry the path and header conditions of the
+	// KIngress, so the root proxy hands it all of the host's requests.
+	root.Spec.Includes = []v1.Include{{
+		Name:      hostProxy.Name,
+		Namespace: hostProxy.Namespace,
+		Conditions: []v1.MatchCondition{{
+			Prefix: "/",
+		}},
+	}}
+
+	// References without a namespace are relative to the KIngress, not to the root proxy.
+	vhost := root.Spec.VirtualHost
+	if vhost.Authorization != nil && vhost.Authorization.ExtensionServiceRef.Namespace == "" {
+		vhost.Authorization.ExtensionServiceRef.Namespace = ing.Namespace
+	}
+	if vhost.TLS != nil && vhost.TLS.ClientValidation != nil {
+		cv := vhost.TLS.ClientValidation
+		cv.CACertificate = qualifiedSecret(ing.Namespace, cv.CACertificate)
+		cv.CertificateRevocationList = qualifiedSecret(ing.Namespace, cv.CertificateRevocationList)
+	}
+	return root
+}
+
+// qualifiedSecret returns the secret reference in the namespace/name form, using the
+// given namespace when it has none.
+func qualifiedSecret(namespace, secret string) string {
+	if secret == "" || strings.Contains(secret, "/") {
+		return secret
+	}
+	return namespace + "/" + secret
+}
+
+// setClass assigns the proxy its Contour class with the class annotation,
+// Spec.IngressClassName or both, depending on the mode.  Switching modes updates
+// the proxy in place, so Contour never stops serving it.  The class label, which
+// our selectors rely on, is always set.
+func setClass(proxy *v1.HTTPProxy, mode config.IngressClassMode, class string) {
+	proxy.Labels[ClassKey] = class
+	if mode.UsesAnnotation() {
+		proxy.Annotations[ClassKey] = class
+	} else {
+		delete(proxy.Annotations, ClassKey)
+	}
+	if mode.UsesField() {
+		proxy.Spec.IngressClassName = class
+	}
+}
+
+// proxyClass returns the Contour class of the proxy, whichever way it was assigned.
+func proxyClass(proxy *v1.HTTPProxy) string {
+	if class := proxy.Annotations[ClassKey]; class != "" {
+		return class
+	}
+	if class := proxy.Spec.IngressClassName; class != "" {
+		return class
+	}
+	return proxy.Labels[ClassKey]
+}
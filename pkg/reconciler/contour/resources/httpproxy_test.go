@@ -19,6 +19,7 @@ package resources
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"knative.dev/pkg/system"
@@ -33,6 +34,8 @@ import (
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	netcfg "knative.dev/networking/pkg/config"
+	netheader "knative.dev/networking/pkg/http/header"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/network"
 	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/reconciler"
@@ -130,6 +133,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -273,6 +281,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -343,6 +356,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -413,6 +431,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -529,6 +552,11 @@ func TestMakeProxies(t *testing.T) {
 							Name:  "tag",
 							Exact: "goo",
 						},
+					}, {
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
 					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
@@ -562,6 +590,11 @@ func TestMakeProxies(t *testing.T) {
 							Name:  "tag",
 							Exact: "doo",
 						},
+					}, {
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
 					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
@@ -721,6 +754,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -892,6 +930,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -980,6 +1023,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1062,6 +1110,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1144,6 +1197,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1276,6 +1334,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1442,6 +1505,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1524,6 +1592,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1640,6 +1713,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1753,6 +1831,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -1877,12 +1960,19 @@ func TestMakeProxies(t *testing.T) {
 				Routes: []v1.Route{{
 					EnableWebsockets: true,
 					PermitInsecure:   true,
+					// The probe is never sent to the authorization server.
+					AuthPolicy: &v1.AuthorizationPolicy{Disabled: true},
 					TimeoutPolicy: &v1.TimeoutPolicy{
 						Response: "infinity",
 						Idle:     "infinity",
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -2004,6 +2094,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -2077,6 +2172,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -2150,6 +2250,11 @@ func TestMakeProxies(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -2299,6 +2404,11 @@ func TestMakeProxiesSystemInternalEncryption(t *testing.T) {
 						},
 						RetryPolicy: defaultRetryPolicy(),
 						Conditions: []v1.MatchCondition{{
+							Header: &v1.HeaderMatchCondition{
+								Name:  "K-Network-Probe",
+								Exact: "probe",
+							},
+						}, {
 							Header: &v1.HeaderMatchCondition{
 								Name:  "K-Network-Hash",
 								Exact: "override",
@@ -2444,6 +2554,11 @@ func TestMakeProxiesSystemInternalEncryption(t *testing.T) {
 						},
 						RetryPolicy: defaultRetryPolicy(),
 						Conditions: []v1.MatchCondition{{
+							Header: &v1.HeaderMatchCondition{
+								Name:  "K-Network-Probe",
+								Exact: "probe",
+							},
+						}, {
 							Header: &v1.HeaderMatchCondition{
 								Name:  "K-Network-Hash",
 								Exact: "override",
@@ -2614,6 +2729,11 @@ func TestMakeProxiesSystemInternalEncryption(t *testing.T) {
 							}},
 						},
 						Conditions: []v1.MatchCondition{{
+							Header: &v1.HeaderMatchCondition{
+								Name:  "K-Network-Probe",
+								Exact: "probe",
+							},
+						}, {
 							Header: &v1.HeaderMatchCondition{
 								Name:  "K-Network-Hash",
 								Exact: "override",
@@ -2658,6 +2778,11 @@ func TestMakeProxiesSystemInternalEncryption(t *testing.T) {
 						},
 						Conditions: []v1.MatchCondition{{
 							Prefix: "/.well-known/acme-challenge/some-challenge",
+						}, {
+							Header: &v1.HeaderMatchCondition{
+								Name:  "K-Network-Probe",
+								Exact: "probe",
+							},
 						}, {
 							Header: &v1.HeaderMatchCondition{
 								Name:  "K-Network-Hash",
@@ -2868,6 +2993,11 @@ func TestMakeProxiesCORSPolicy(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -3013,6 +3143,11 @@ func TestMakeProxiesCORSPolicy(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -3095,6 +3230,11 @@ func TestMakeProxiesCORSPolicy(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -3177,6 +3317,11 @@ func TestMakeProxiesCORSPolicy(t *testing.T) {
 					},
 					RetryPolicy: defaultRetryPolicy(),
 					Conditions: []v1.MatchCondition{{
+						Header: &v1.HeaderMatchCondition{
+							Name:  "K-Network-Probe",
+							Exact: "probe",
+						},
+					}, {
 						Header: &v1.HeaderMatchCondition{
 							Name:  "K-Network-Hash",
 							Exact: "override",
@@ -3264,6 +3409,841 @@ func TestMakeProxiesCORSPolicy(t *testing.T) {
 	}
 }
 
+func TestMakeProxiesCrossNamespace(t *testing.T) {
+	ing := testIngress(func(ing *v1alpha1.Ingress) {
+		ing.Spec.Rules[0].HTTP.Paths[0].Splits = []v1alpha1.IngressBackendSplit{{
+			IngressBackend: v1alpha1.IngressBackend{
+				ServiceName:      "goo",
+				ServiceNamespace: "foo",
+				ServicePort:      intstr.FromInt(123),
+			},
+			Percent: 50,
+		}, {
+			IngressBackend: v1alpha1.IngressBackend{
+				ServiceName:      "goo",
+				ServiceNamespace: "shared",
+				ServicePort:      intstr.FromInt(124),
+			},
+			Percent: 50,
+		}}
+	})
+	ctx := testContext(func(cfg *config.Config) {
+		cfg.Network = &netcfg.Config{
+			SystemInternalTLS: netcfg.EncryptionEnabled,
+		}
+	})
+
+	proxies := MakeHTTPProxies(ctx, ing, map[string]string{
+		"goo":        "h2c",
+		"shared/goo": InternalEncryptionProtocol,
+	})
+	if len(proxies) != 1 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+	}
+
+	// The last route is the one for our path, the first is the probe.
+	got := proxies[0].Spec.Routes[len(proxies[0].Spec.Routes)-1].Services
+	want := []v1.Service{{
+		Name:     "goo",
+		Port:     123,
+		Weight:   50,
+		Protocol: ptr.String("h2c"),
+		UpstreamValidation: &v1.UpstreamValidation{
+			CACertificate: fmt.Sprintf("%s/%s", system.Namespace(), netcfg.ServingRoutingCertName),
+			SubjectName:   "kn-user-foo",
+			SubjectNames:  []string{"kn-user-foo", "kn-routing"},
+		},
+	}, {
+		Name:     "bar-goo-shared",
+		Port:     124,
+		Weight:   50,
+		Protocol: ptr.String(InternalEncryptionProtocol),
+		UpstreamValidation: &v1.UpstreamValidation{
+			CACertificate: fmt.Sprintf("%s/%s", system.Namespace(), netcfg.ServingRoutingCertName),
+			SubjectName:   "kn-user-shared",
+			SubjectNames:  []string{"kn-user-shared", "kn-routing"},
+		},
+	}}
+	if !cmp.Equal(want, got) {
+		t.Error("MakeHTTPProxies services (-want, +got) =", cmp.Diff(want, got))
+	}
+}
+
+func TestMakeProxiesRetryPolicy(t *testing.T) {
+	configured := &v1.RetryPolicy{
+		NumRetries:           3,
+		PerTryTimeout:        "2s",
+		RetryOn:              []v1.RetryOn{"retriable-status-codes"},
+		RetriableStatusCodes: []uint32{503},
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		config      *v1.RetryPolicy
+		want        *v1.RetryPolicy
+	}{{
+		name: "default",
+		want: defaultRetryPolicy(),
+	}, {
+		name:   "from config",
+		config: configured,
+		want:   configured,
+	}, {
+		name:        "annotation overrides config",
+		annotations: map[string]string{RetryPolicyKey: "count: -1"},
+		config:      configured,
+		want:        &v1.RetryPolicy{NumRetries: -1},
+	}, {
+		name:        "invalid annotation falls back on config",
+		annotations: map[string]string{RetryPolicyKey: "count: -2"},
+		config:      configured,
+		want:        configured,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := testIngress(withAnnotations(tc.annotations))
+			ctx := testContext(func(cfg *config.Config) {
+				cfg.Contour.RetryPolicy = tc.config
+			})
+
+			proxies := MakeHTTPProxies(ctx, ing, nil)
+			if len(proxies) != 1 {
+				t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+			}
+			for _, route := range proxies[0].Spec.Routes {
+				if !cmp.Equal(tc.want, route.RetryPolicy) {
+					t.Error("RetryPolicy (-want, +got) =", cmp.Diff(tc.want, route.RetryPolicy))
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesTimeoutAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        *v1.TimeoutPolicy
+	}{{
+		name: "from config",
+		want: &v1.TimeoutPolicy{
+			Response: "infinity",
+			Idle:     "infinity",
+		},
+	}, {
+		name: "annotations override config",
+		annotations: map[string]string{
+			TimeoutResponseKey:       "15s",
+			TimeoutIdleKey:           "5m",
+			TimeoutIdleConnectionKey: "90s",
+		},
+		want: &v1.TimeoutPolicy{
+			Response:       "15s",
+			Idle:           "5m",
+			IdleConnection: "90s",
+		},
+	}, {
+		name: "invalid annotation falls back on config",
+		annotations: map[string]string{
+			TimeoutResponseKey: "forever",
+		},
+		want: &v1.TimeoutPolicy{
+			Response: "infinity",
+			Idle:     "infinity",
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := testIngress(withAnnotations(tc.annotations))
+			ctx := testContext(func(cfg *config.Config) {
+				cfg.Contour.TimeoutPolicyResponse = "infinity"
+				cfg.Contour.TimeoutPolicyIdle = "infinity"
+			})
+
+			proxies := MakeHTTPProxies(ctx, ing, nil)
+			if len(proxies) != 1 {
+				t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+			}
+			for _, route := range proxies[0].Spec.Routes {
+				if !cmp.Equal(tc.want, route.TimeoutPolicy) {
+					t.Error("TimeoutPolicy (-want, +got) =", cmp.Diff(tc.want, route.TimeoutPolicy))
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesLocalRateLimit(t *testing.T) {
+	configured := &v1.LocalRateLimitPolicy{
+		Requests: 100,
+		Unit:     "second",
+		Burst:    10,
+	}
+
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		config       *v1.LocalRateLimitPolicy
+		visibilities sets.Set[v1alpha1.IngressVisibility]
+		want         map[v1alpha1.IngressVisibility]*v1.RateLimitPolicy
+	}{{
+		name:         "not configured",
+		visibilities: sets.New(v1alpha1.IngressVisibilityExternalIP),
+		want:         map[v1alpha1.IngressVisibility]*v1.RateLimitPolicy{},
+	}, {
+		name:         "from config",
+		config:       configured,
+		visibilities: sets.New(v1alpha1.IngressVisibilityExternalIP),
+		want: map[v1alpha1.IngressVisibility]*v1.RateLimitPolicy{
+			v1alpha1.IngressVisibilityExternalIP: {Local: configured},
+		},
+	}, {
+		name:         "from config for all visibilities",
+		config:       configured,
+		visibilities: sets.New(v1alpha1.IngressVisibilityExternalIP, v1alpha1.IngressVisibilityClusterLocal),
+		want: map[v1alpha1.IngressVisibility]*v1.RateLimitPolicy{
+			v1alpha1.IngressVisibilityExternalIP:   {Local: configured},
+			v1alpha1.IngressVisibilityClusterLocal: {Local: configured},
+		},
+	}, {
+		name:         "annotation overrides config",
+		annotations:  map[string]string{LocalRateLimitKey: "{requests: 5, unit: minute, responseStatusCode: 503}"},
+		config:       configured,
+		visibilities: sets.New(v1alpha1.IngressVisibilityExternalIP),
+		want: map[v1alpha1.IngressVisibility]*v1.RateLimitPolicy{
+			v1alpha1.IngressVisibilityExternalIP: {Local: &v1.LocalRateLimitPolicy{
+				Requests:           5,
+				Unit:               "minute",
+				ResponseStatusCode: 503,
+			}},
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := testIngress(
+				withAnnotations(tc.annotations),
+				withRule("bar.foo.svc.cluster.local", v1alpha1.IngressVisibilityClusterLocal),
+				withPaths(testPath(HTTPChallengePath+"/token")),
+			)
+			ctx := testContext(func(cfg *config.Config) {
+				cfg.Contour.LocalRateLimit = tc.config
+				cfg.Contour.LocalRateLimitVisibilities = tc.visibilities
+			})
+
+			for _, proxy := range MakeHTTPProxies(ctx, ing, nil) {
+				vis := v1alpha1.IngressVisibilityExternalIP
+				if proxy.Annotations[ClassKey] == privateClass {
+					vis = v1alpha1.IngressVisibilityClusterLocal
+				}
+				for _, route := range proxy.Spec.Routes {
+					want := tc.want[vis]
+					// The probes and ACME challenges are never rate limited.
+					for _, cond := range route.Conditions {
+						if cond.Header != nil || cond.Prefix != "" {
+							want = nil
+						}
+					}
+					if !cmp.Equal(want, route.RateLimitPolicy) {
+						t.Errorf("%s %v: RateLimitPolicy (-want, +got) = %s", proxy.Name, route.Conditions, cmp.Diff(want, route.RateLimitPolicy))
+					}
+				}
+
+				// Only our prober skips the rate limit, not anyone who sends the hash header.
+				i := matchRoute(proxy.Spec.Routes, "/", hashHeader)
+				if i < 0 {
+					t.Fatalf("%s: no route for a request with only %s", proxy.Name, netheader.HashKey)
+				}
+				if got := proxy.Spec.Routes[i].RateLimitPolicy; !cmp.Equal(tc.want[vis], got) {
+					t.Errorf("%s: RateLimitPolicy with only %s (-want, +got) = %s", proxy.Name, netheader.HashKey, cmp.Diff(tc.want[vis], got))
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesGlobalRateLimit(t *testing.T) {
+	configured := []v1.RateLimitDescriptor{{
+		Entries: []v1.RateLimitDescriptorEntry{{
+			RemoteAddress: &v1.RemoteAddressDescriptor{},
+		}},
+	}}
+	annotated := []v1.RateLimitDescriptor{{
+		Entries: []v1.RateLimitDescriptorEntry{{
+			GenericKey: &v1.GenericKeyDescriptor{Value: "tenant-a"},
+		}},
+	}}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		config      []v1.RateLimitDescriptor
+		want        map[v1alpha1.IngressVisibility][]v1.RateLimitDescriptor
+	}{{
+		name: "not configured",
+		want: map[v1alpha1.IngressVisibility][]v1.RateLimitDescriptor{},
+	}, {
+		name:   "from config",
+		config: configured,
+		want: map[v1alpha1.IngressVisibility][]v1.RateLimitDescriptor{
+			v1alpha1.IngressVisibilityExternalIP: configured,
+		},
+	}, {
+		name:        "annotation overrides config",
+		annotations: map[string]string{GlobalRateLimitKey: "- entries: [{genericKey: {value: tenant-a}}]"},
+		config:      configured,
+		want: map[v1alpha1.IngressVisibility][]v1.RateLimitDescriptor{
+			v1alpha1.IngressVisibilityExternalIP:   annotated,
+			v1alpha1.IngressVisibilityClusterLocal: annotated,
+		},
+	}, {
+		name:        "invalid annotation falls back on config",
+		annotations: map[string]string{GlobalRateLimitKey: "- entries: []"},
+		config:      configured,
+		want: map[v1alpha1.IngressVisibility][]v1.RateLimitDescriptor{
+			v1alpha1.IngressVisibilityExternalIP: configured,
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := testIngress(
+				withAnnotations(tc.annotations),
+				withRule("bar.foo.svc.cluster.local", v1alpha1.IngressVisibilityClusterLocal),
+			)
+			ctx := testContext(func(cfg *config.Config) {
+				cfg.Contour.GlobalRateLimitDescriptors = tc.config
+			})
+
+			for _, proxy := range MakeHTTPProxies(ctx, ing, nil) {
+				vis := v1alpha1.IngressVisibilityExternalIP
+				if proxy.Annotations[ClassKey] == privateClass {
+					vis = v1alpha1.IngressVisibilityClusterLocal
+				}
+
+				var want *v1.RateLimitPolicy
+				if descriptors, ok := tc.want[vis]; ok {
+					want = &v1.RateLimitPolicy{Global: &v1.GlobalRateLimitPolicy{Descriptors: descriptors}}
+				}
+				if got := proxy.Spec.VirtualHost.RateLimitPolicy; !cmp.Equal(want, got) {
+					t.Errorf("%s: RateLimitPolicy (-want, +got) = %s", proxy.Name, cmp.Diff(want, got))
+				}
+
+				// The probe route opts out of the global rate limit of the virtual host.
+				var wantProbe *v1.RateLimitPolicy
+				if want != nil {
+					wantProbe = &v1.RateLimitPolicy{Global: &v1.GlobalRateLimitPolicy{Disabled: true}}
+				}
+				if got := proxy.Spec.Routes[0].RateLimitPolicy; !cmp.Equal(wantProbe, got) {
+					t.Errorf("%s: probe RateLimitPolicy (-want, +got) = %s", proxy.Name, cmp.Diff(wantProbe, got))
+				}
+				if got := proxy.Spec.Routes[1].RateLimitPolicy; got != nil {
+					t.Errorf("%s: route RateLimitPolicy = %v, want nil", proxy.Name, got)
+				}
+
+				// Sending the hash header of the probe alone does not opt out.
+				if got := matchRoute(proxy.Spec.Routes, "/", hashHeader); got != 1 {
+					t.Errorf("%s: request with only %s takes Routes[%d], wanted the rate limited route", proxy.Name, netheader.HashKey, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesIPFilter(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		config      map[v1alpha1.IngressVisibility]config.IPFilter
+		wantAllow   map[v1alpha1.IngressVisibility][]v1.IPFilterPolicy
+		wantDeny    map[v1alpha1.IngressVisibility][]v1.IPFilterPolicy
+	}{{
+		name: "not configured",
+	}, {
+		name: "from config",
+		config: map[v1alpha1.IngressVisibility]config.IPFilter{
+			v1alpha1.IngressVisibilityClusterLocal: {
+				Allow:  []string{"10.0.0.0/8"},
+				Source: v1.IPFilterSourcePeer,
+			},
+			v1alpha1.IngressVisibilityExternalIP: {
+				Deny: []string{"192.0.2.0/24"},
+			},
+		},
+		wantAllow: map[v1alpha1.IngressVisibility][]v1.IPFilterPolicy{
+			v1alpha1.IngressVisibilityClusterLocal: {{Source: v1.IPFilterSourcePeer, CIDR: "10.0.0.0/8"}},
+		},
+		wantDeny: map[v1alpha1.IngressVisibility][]v1.IPFilterPolicy{
+			v1alpha1.IngressVisibilityExternalIP: {{Source: v1.IPFilterSourceRemote, CIDR: "192.0.2.0/24"}},
+		},
+	}, {
+		name: "annotations override config",
+		annotations: map[string]string{
+			IPAllowKey:        "172.16.0.0/12, 2001:db8::/32",
+			IPFilterSourceKey: "Peer",
+		},
+		config: map[v1alpha1.IngressVisibility]config.IPFilter{
+			v1alpha1.IngressVisibilityExternalIP: {
+				Deny: []string{"192.0.2.0/24"},
+			},
+		},
+		wantAllow: map[v1alpha1.IngressVisibility][]v1.IPFilterPolicy{
+			v1alpha1.IngressVisibilityClusterLocal: {
+				{Source: v1.IPFilterSourcePeer, CIDR: "172.16.0.0/12"},
+				{Source: v1.IPFilterSourcePeer, CIDR: "2001:db8::/32"},
+			},
+			v1alpha1.IngressVisibilityExternalIP: {
+				{Source: v1.IPFilterSourcePeer, CIDR: "172.16.0.0/12"},
+				{Source: v1.IPFilterSourcePeer, CIDR: "2001:db8::/32"},
+			},
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ing := testIngress(
+				withAnnotations(tc.annotations),
+				withRule("bar.foo.svc.cluster.local", v1alpha1.IngressVisibilityClusterLocal),
+			)
+			ctx := testContext(func(cfg *config.Config) {
+				cfg.Contour.IPFilters = tc.config
+			})
+
+			for _, proxy := range MakeHTTPProxies(ctx, ing, nil) {
+				vis := v1alpha1.IngressVisibilityExternalIP
+				if proxy.Annotations[ClassKey] == privateClass {
+					vis = v1alpha1.IngressVisibilityClusterLocal
+				}
+
+				// The probe route is never filtered, but only our prober takes it.
+				probe := proxy.Spec.Routes[0]
+				if probe.IPAllowFilterPolicy != nil || probe.IPDenyFilterPolicy != nil {
+					t.Errorf("%s: probe route is filtered: %v, %v", proxy.Name, probe.IPAllowFilterPolicy, probe.IPDenyFilterPolicy)
+				}
+				if got := matchRoute(proxy.Spec.Routes, "/", probeHeaders); got != 0 {
+					t.Errorf("%s: probe takes Routes[%d], wanted the probe route", proxy.Name, got)
+				}
+				if got := matchRoute(proxy.Spec.Routes, "/", hashHeader); got != 1 {
+					t.Errorf("%s: request with only %s takes Routes[%d], wanted the filtered route", proxy.Name, netheader.HashKey, got)
+				}
+				route := proxy.Spec.Routes[1]
+				if !cmp.Equal(tc.wantAllow[vis], route.IPAllowFilterPolicy) {
+					t.Errorf("%s: IPAllowFilterPolicy (-want, +got) = %s", proxy.Name, cmp.Diff(tc.wantAllow[vis], route.IPAllowFilterPolicy))
+				}
+				if !cmp.Equal(tc.wantDeny[vis], route.IPDenyFilterPolicy) {
+					t.Errorf("%s: IPDenyFilterPolicy (-want, +got) = %s", proxy.Name, cmp.Diff(tc.wantDeny[vis], route.IPDenyFilterPolicy))
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesJWTProvider(t *testing.T) {
+	corp := v1.JWTProvider{
+		Name:      "corp",
+		Issuer:    "https://auth.example.com",
+		Audiences: []string{"knative"},
+		RemoteJWKS: v1.RemoteJWKS{
+			URI:           "https://auth.example.com/jwks.json",
+			CacheDuration: "10m",
+		},
+	}
+	ing := testIngress(withPaths(testPath(HTTPChallengePath+"/token")), withTLS)
+	ctx := testContext(func(cfg *config.Config) {
+		cfg.Contour.JWTProviders = map[string]v1.JWTProvider{"corp": corp}
+	})
+
+	// Without the annotation, no JWT is required.
+	proxies := MakeHTTPProxies(ctx, ing, nil)
+	if len(proxies) != 1 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+	}
+	if got := proxies[0].Spec.VirtualHost.JWTProviders; got != nil {
+		t.Errorf("JWTProviders = %v, wanted nil", got)
+	}
+	for _, route := range proxies[0].Spec.Routes {
+		if route.JWTVerificationPolicy != nil {
+			t.Errorf("JWTVerificationPolicy = %v, wanted nil", route.JWTVerificationPolicy)
+		}
+	}
+
+	ing.Annotations = map[string]string{JWTProviderKey: "corp"}
+	proxies = MakeHTTPProxies(ctx, ing, nil)
+	if len(proxies) != 1 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+	}
+	wantProvider := *corp.DeepCopy()
+	wantProvider.Default = true
+	if got, want := proxies[0].Spec.VirtualHost.JWTProviders, []v1.JWTProvider{wantProvider}; !cmp.Equal(want, got) {
+		t.Error("JWTProviders (-want, +got) =", cmp.Diff(want, got))
+	}
+
+	// The routes are the two probes, the ACME challenge and the service's,
+	// and only the latter requires a JWT.
+	routes := proxies[0].Spec.Routes
+	if len(routes) != 4 {
+		t.Fatalf("len(Routes) = %d, wanted 4", len(routes))
+	}
+	exempt := &v1.JWTVerificationPolicy{Disabled: true}
+	for i, want := range []*v1.JWTVerificationPolicy{exempt, exempt, exempt, nil} {
+		if got := routes[i].JWTVerificationPolicy; !cmp.Equal(want, got) {
+			t.Errorf("Routes[%d] %v: JWTVerificationPolicy = %v, wanted %v", i, routes[i].Conditions, got, want)
+		}
+	}
+	// Only our prober skips the JWT, not anyone who sends the hash header.
+	if got := matchRoute(routes, "/", probeHeaders); got != 1 {
+		t.Errorf("probe takes Routes[%d], wanted Routes[1]", got)
+	}
+	if got := matchRoute(routes, "/", hashHeader); got != 3 {
+		t.Errorf("request with only %s takes Routes[%d], wanted Routes[3]", netheader.HashKey, got)
+	}
+
+	// Contour does not verify JWTs on virtual hosts without TLS, such as
+	// cluster-local ones, so they neither get providers nor route policies.
+	for _, test := range []struct {
+		name string
+		ing  func(*v1alpha1.Ingress)
+	}{{
+		name: "without TLS",
+		ing: func(ing *v1alpha1.Ingress) {
+			ing.Spec.TLS = nil
+		},
+	}, {
+		name: "cluster-local",
+		ing: func(ing *v1alpha1.Ingress) {
+			ing.Spec.TLS = nil
+			ing.Spec.Rules[0].Hosts = []string{"bar.foo.svc.cluster.local"}
+			ing.Spec.Rules[0].Visibility = v1alpha1.IngressVisibilityClusterLocal
+		},
+	}} {
+		t.Run(test.name, func(t *testing.T) {
+			ing := ing.DeepCopy()
+			test.ing(ing)
+			proxies := MakeHTTPProxies(ctx, ing, nil)
+			if len(proxies) == 0 {
+				t.Fatal("MakeHTTPProxies returned no proxies")
+			}
+			for _, proxy := range proxies {
+				if got := proxy.Spec.VirtualHost.JWTProviders; got != nil {
+					t.Errorf("%s: JWTProviders = %v, wanted nil", proxy.Spec.VirtualHost.Fqdn, got)
+				}
+				for _, route := range proxy.Spec.Routes {
+					if route.JWTVerificationPolicy != nil {
+						t.Errorf("%s: Route %v: JWTVerificationPolicy = %v, wanted nil", proxy.Spec.VirtualHost.Fqdn, route.Conditions, route.JWTVerificationPolicy)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesExtensionServiceOptions(t *testing.T) {
+	ing := testIngress(withAnnotations(map[string]string{
+		ExtensionServiceKey:          "es",
+		ExtensionServiceNamespaceKey: "es-ns",
+		ExtensionServiceOptionsKey: `
+responseTimeout: 500ms
+failOpen: true
+withRequestBody:
+  maxRequestBytes: 1024
+  allowPartialMessage: true
+context:
+  tenant: a
+disabledPaths:
+- /healthz
+`,
+	}), withPaths(testPath("/healthz")))
+	ctx := testContext(nil)
+
+	proxies := MakeHTTPProxies(ctx, ing, nil)
+	if len(proxies) != 1 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+	}
+
+	want := &v1.AuthorizationServer{
+		ExtensionServiceRef: v1.ExtensionServiceReference{
+			Name:      "es",
+			Namespace: "es-ns",
+		},
+		ResponseTimeout: "500ms",
+		FailOpen:        true,
+		WithRequestBody: &v1.AuthorizationServerBufferSettings{
+			MaxRequestBytes:     1024,
+			AllowPartialMessage: true,
+		},
+		AuthPolicy: &v1.AuthorizationPolicy{
+			Context: map[string]string{"tenant": "a"},
+		},
+	}
+	if got := proxies[0].Spec.VirtualHost.Authorization; !cmp.Equal(want, got) {
+		t.Error("Authorization (-want, +got) =", cmp.Diff(want, got))
+	}
+
+	// The routes are the two probes, /healthz and the service's, and only
+	// the latter is sent to the authorization server.
+	routes := proxies[0].Spec.Routes
+	if len(routes) != 4 {
+		t.Fatalf("len(Routes) = %d, wanted 4", len(routes))
+	}
+	skip := &v1.AuthorizationPolicy{Disabled: true}
+	for i, want := range []*v1.AuthorizationPolicy{skip, skip, skip, nil} {
+		if got := routes[i].AuthPolicy; !cmp.Equal(want, got) {
+			t.Errorf("Routes[%d] %v: AuthPolicy = %v, wanted %v", i, routes[i].Conditions, got, want)
+		}
+	}
+	// Only our prober skips the authorization server, not anyone who sends
+	// the hash header.
+	if got := matchRoute(routes, "/", probeHeaders); got != 1 {
+		t.Errorf("probe takes Routes[%d], wanted Routes[1]", got)
+	}
+	if got := matchRoute(routes, "/", hashHeader); got != 3 {
+		t.Errorf("request with only %s takes Routes[%d], wanted Routes[3]", netheader.HashKey, got)
+	}
+}
+
+func TestMakeProxiesDefaultAuthorization(t *testing.T) {
+	defaultAuth := &v1.AuthorizationServer{
+		ExtensionServiceRef: v1.ExtensionServiceReference{
+			Namespace: "auth",
+			Name:      "authz",
+		},
+		ResponseTimeout: "1s",
+	}
+	ctx := testContext(func(cfg *config.Config) {
+		cfg.Contour.DefaultAuthorization = defaultAuth
+	})
+
+	tests := []struct {
+		name             string
+		annotations      map[string]string
+		withoutTLS       bool
+		wantExternal     *v1.AuthorizationServer
+		wantClusterLocal *v1.AuthorizationServer
+	}{{
+		name:         "default",
+		wantExternal: defaultAuth,
+	}, {
+		name: "opted out",
+		annotations: map[string]string{
+			DisableDefaultAuthorizationKey: "true",
+		},
+	}, {
+		name: "not opted out",
+		annotations: map[string]string{
+			DisableDefaultAuthorizationKey: "false",
+		},
+		wantExternal: defaultAuth,
+	}, {
+		name:       "external host without TLS",
+		withoutTLS: true,
+	}, {
+		name: "endpoints probe",
+		annotations: map[string]string{
+			EndpointsProbeKey: "true",
+		},
+	}, {
+		name: "own extension service",
+		annotations: map[string]string{
+			ExtensionServiceKey: "es",
+		},
+		wantExternal: &v1.AuthorizationServer{
+			ExtensionServiceRef: v1.ExtensionServiceReference{Name: "es"},
+		},
+		wantClusterLocal: &v1.AuthorizationServer{
+			ExtensionServiceRef: v1.ExtensionServiceReference{Name: "es"},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing := testIngress(
+				withAnnotations(test.annotations),
+				withRule("bar.foo.svc.cluster.local", v1alpha1.IngressVisibilityClusterLocal),
+			)
+			if !test.withoutTLS {
+				withTLS(ing)
+			}
+
+			// The cluster-local host may yield several proxies.
+			proxies := MakeHTTPProxies(ctx, ing, nil)
+			if len(proxies) < 2 {
+				t.Fatalf("len(MakeHTTPProxies) = %d, wanted at least 2", len(proxies))
+			}
+			for _, proxy := range proxies {
+				want := test.wantClusterLocal
+				if proxy.Annotations[ClassKey] == publicClass {
+					want = test.wantExternal
+				}
+				if got := proxy.Spec.VirtualHost.Authorization; !cmp.Equal(want, got) {
+					t.Errorf("%s: Authorization (-want, +got) = %s", proxy.Spec.VirtualHost.Fqdn, cmp.Diff(want, got))
+				}
+				if want == nil {
+					for _, route := range proxy.Spec.Routes {
+						if route.AuthPolicy != nil {
+							t.Errorf("%s: route %v has AuthPolicy %v without authorization", proxy.Spec.VirtualHost.Fqdn, route.Conditions, route.AuthPolicy)
+						}
+					}
+					continue
+				}
+				// The probes are never sent to the authorization server.
+				for _, route := range proxy.Spec.Routes {
+					isProbe := len(route.Conditions) > 0 && route.Conditions[0].Header != nil
+					if isProbe && (route.AuthPolicy == nil || !route.AuthPolicy.Disabled) {
+						t.Errorf("%s: probe route %v is authorized", proxy.Spec.VirtualHost.Fqdn, route.Conditions)
+					}
+				}
+				// Requests sending only the hash header of the probe are.
+				if i := matchRoute(proxy.Spec.Routes, "/", hashHeader); i < 0 || proxy.Spec.Routes[i].AuthPolicy != nil {
+					t.Errorf("%s: request with only %s skips the authorization server", proxy.Spec.VirtualHost.Fqdn, netheader.HashKey)
+				}
+			}
+		})
+	}
+}
+
+func TestMakeProxiesDownstreamTLS(t *testing.T) {
+	ing := testIngress(
+		withAnnotations(map[string]string{
+			TLSMinimumProtocolVersionKey: "1.3",
+		}),
+		withRule("plain.example.com", v1alpha1.IngressVisibilityExternalIP),
+		withTLS,
+		func(ing *v1alpha1.Ingress) {
+			ing.Spec.HTTPOption = v1alpha1.HTTPOptionRedirected
+		},
+	)
+	ctx := testContext(func(cfg *config.Config) {
+		cfg.Contour.DownstreamTLS = &config.DownstreamTLS{
+			MinimumProtocolVersion: "1.2",
+			ClientValidation: &v1.DownstreamValidation{
+				CACertificate: "partners/ca",
+			},
+		}
+	})
+
+	proxies := MakeHTTPProxies(ctx, ing, nil)
+	if len(proxies) != 2 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 2", len(proxies))
+	}
+
+	for _, proxy := range proxies {
+		vhost := proxy.Spec.VirtualHost
+		var want *v1.TLS
+		if vhost.Fqdn == "example.com" {
+			want = &v1.TLS{
+				SecretName:             "secretns/secretname",
+				MinimumProtocolVersion: "1.3",
+				ClientValidation: &v1.DownstreamValidation{
+					CACertificate: "partners/ca",
+				},
+			}
+		}
+		if !cmp.Equal(want, vhost.TLS) {
+			t.Errorf("%s: TLS (-want, +got) = %s", vhost.Fqdn, cmp.Diff(want, vhost.TLS))
+		}
+
+		// The probe is served over plain HTTP, but only to our prober.
+		probe, route := proxy.Spec.Routes[0], proxy.Spec.Routes[1]
+		wantConditions := []v1.MatchCondition{{
+			Header: &v1.HeaderMatchCondition{Name: netheader.ProbeKey, Exact: netheader.ProbeValue},
+		}, {
+			Header: &v1.HeaderMatchCondition{Name: netheader.HashKey, Exact: netheader.HashValueOverride},
+		}}
+		if !cmp.Equal(wantConditions, probe.Conditions) {
+			t.Errorf("%s: probe Conditions (-want, +got) = %s", vhost.Fqdn, cmp.Diff(wantConditions, probe.Conditions))
+		}
+		if !probe.PermitInsecure {
+			t.Errorf("%s: probe PermitInsecure = false, wanted true", vhost.Fqdn)
+		}
+		if route.PermitInsecure {
+			t.Errorf("%s: PermitInsecure = true, wanted false", vhost.Fqdn)
+		}
+	}
+}
+
+func TestMakeProxiesDefaultTLSSecrets(t *testing.T) {
+	ctx := testContext(func(cfg *config.Config) {
+		cfg.Contour.DefaultTLSSecret = &types.NamespacedName{Namespace: "certs", Name: "fallback"}
+		cfg.Contour.DefaultTLSSecrets = map[string]types.NamespacedName{
+			"*.example.com":            {Namespace: "certs", Name: "wildcard"},
+			"*.apps.example.com":       {Namespace: "certs", Name: "apps"},
+			"special.apps.example.com": {Namespace: "certs", Name: "special"},
+		}
+	})
+	ing := testIngress(func(ing *v1alpha1.Ingress) {
+		ing.Spec.Rules[0].Hosts = []string{"a.example.com", "b.apps.example.com", "special.apps.example.com", "other.com"}
+	})
+
+	want := map[string]string{
+		"a.example.com":            "certs/wildcard",
+		"b.apps.example.com":       "certs/apps",
+		"special.apps.example.com": "certs/special",
+		"other.com":                "certs/fallback",
+	}
+	got := make(map[string]string, len(want))
+	for _, proxy := range MakeHTTPProxies(ctx, ing, nil) {
+		if tls := proxy.Spec.VirtualHost.TLS; tls != nil {
+			got[proxy.Spec.VirtualHost.Fqdn] = tls.SecretName
+		}
+	}
+	if !cmp.Equal(want, got) {
+		t.Error("TLS secrets (-want, +got) =", cmp.Diff(want, got))
+	}
+}
+
+func TestMakeProxiesIngressClassMode(t *testing.T) {
+	// A class annotation on the KIngress never overrides ours.
+	ing := testIngress(withAnnotations(map[string]string{ClassKey: "other"}))
+
+	tests := []struct {
+		mode           config.IngressClassMode
+		wantAnnotation string
+		wantField      string
+	}{{
+		mode:           "",
+		wantAnnotation: publicClass,
+	}, {
+		mode:           config.IngressClassModeAnnotation,
+		wantAnnotation: publicClass,
+	}, {
+		mode:      config.IngressClassModeField,
+		wantField: publicClass,
+	}, {
+		mode:           config.IngressClassModeBoth,
+		wantAnnotation: publicClass,
+		wantField:      publicClass,
+	}}
+
+	for _, test := range tests {
+		t.Run(string(test.mode), func(t *testing.T) {
+			ctx := testContext(func(cfg *config.Config) {
+				cfg.Contour.IngressClassMode = test.mode
+			})
+
+			proxies := MakeHTTPProxies(ctx, ing, nil)
+			if len(proxies) != 1 {
+				t.Fatalf("len(MakeHTTPProxies) = %d, wanted 1", len(proxies))
+			}
+			proxy := proxies[0]
+			if got := proxy.Annotations[ClassKey]; got != test.wantAnnotation {
+				t.Errorf("class annotation = %q, wanted %q", got, test.wantAnnotation)
+			}
+			if got := proxy.Spec.IngressClassName; got != test.wantField {
+				t.Errorf("IngressClassName = %q, wanted %q", got, test.wantField)
+			}
+			if got := proxy.Labels[ClassKey]; got != publicClass {
+				t.Errorf("class label = %q, wanted %q", got, publicClass)
+			}
+			if got := proxyClass(proxy); got != publicClass {
+				t.Errorf("proxyClass = %q, wanted %q", got, publicClass)
+			}
+		})
+	}
+}
+
 func TestServiceNames(t *testing.T) {
 	tests := []struct {
 		name string
@@ -3311,6 +4291,40 @@ func TestServiceNames(t *testing.T) {
 			},
 		},
 		want: sets.New("goo", "boo", "doo"),
+	}, {
+		name: "cross-namespace",
+		ing: &v1alpha1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+			},
+			Spec: v1alpha1.IngressSpec{
+				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"example.com"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceName:      "goo",
+									ServiceNamespace: "foo",
+									ServicePort:      intstr.FromInt(123),
+								},
+								Percent: 12,
+							}, {
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceName:      "goo",
+									ServiceNamespace: "shared",
+									ServicePort:      intstr.FromInt(124),
+								},
+								Percent: 88,
+							}},
+						}},
+					},
+				}},
+			},
+		},
+		want: sets.New("goo", "shared/goo"),
 	}}
 
 	for _, test := range tests {
@@ -3333,3 +4347,197 @@ func (t *testConfigStore) ToContext(ctx context.Context) context.Context {
 }
 
 var _ reconciler.ConfigStore = (*testConfigStore)(nil)
+
+// testContext returns a context with the config of our visibility classes,
+// after modify, if any, changed it.
+func testContext(modify func(*config.Config)) context.Context {
+	cfg := &config.Config{
+		Contour: &config.Contour{
+			VisibilityClasses: map[v1alpha1.IngressVisibility]string{
+				v1alpha1.IngressVisibilityClusterLocal: privateClass,
+				v1alpha1.IngressVisibilityExternalIP:   publicClass,
+			},
+		},
+	}
+	if modify != nil {
+		modify(cfg)
+	}
+	return (&testConfigStore{config: cfg}).ToContext(context.Background())
+}
+
+type ingressOption func(*v1alpha1.Ingress)
+
+// testIngress returns the KIngress foo/bar, which routes example.com to goo,
+// with the given options applied.
+func testIngress(opts ...ingressOption) *v1alpha1.Ingress {
+	ing := &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "bar",
+		},
+	}
+	withRule("example.com", v1alpha1.IngressVisibilityExternalIP)(ing)
+	for _, opt := range opts {
+		opt(ing)
+	}
+	return ing
+}
+
+// testPath returns a path that routes the prefix to goo.
+func testPath(prefix string) v1alpha1.HTTPIngressPath {
+	return v1alpha1.HTTPIngressPath{
+		Path: prefix,
+		Splits: []v1alpha1.IngressBackendSplit{{
+			IngressBackend: v1alpha1.IngressBackend{
+				ServiceName: "goo",
+				ServicePort: intstr.FromInt(123),
+			},
+			Percent: 100,
+		}},
+	}
+}
+
+// withRule adds a rule that routes the host to goo.
+func withRule(host string, visibility v1alpha1.IngressVisibility) ingressOption {
+	return func(ing *v1alpha1.Ingress) {
+		ing.Spec.Rules = append(ing.Spec.Rules, v1alpha1.IngressRule{
+			Hosts:      []string{host},
+			Visibility: visibility,
+			HTTP: &v1alpha1.HTTPIngressRuleValue{
+				Paths: []v1alpha1.HTTPIngressPath{testPath("")},
+			},
+		})
+	}
+}
+
+// withPaths adds the paths in front of those of every rule.
+func withPaths(paths ...v1alpha1.HTTPIngressPath) ingressOption {
+	return func(ing *v1alpha1.Ingress) {
+		for _, rule := range ing.Spec.Rules {
+			rule.HTTP.Paths = append(append([]v1alpha1.HTTPIngressPath{}, paths...), rule.HTTP.Paths...)
+		}
+	}
+}
+
+// matchRoute returns the index of the route Contour picks for a request to the
+// path with the headers: of the routes whose conditions all match, the one with
+// the longest prefix and then the most header conditions, or -1 when none match.
+func matchRoute(routes []v1.Route, path string, headers map[string]string) int {
+	match, matchPrefix, matchHeaders := -1, 0, 0
+	for i, route := range routes {
+		ok, prefix, headerCount := true, 0, 0
+		for _, cond := range route.Conditions {
+			if cond.Prefix != "" {
+				ok = ok && strings.HasPrefix(path, cond.Prefix)
+				prefix = len(cond.Prefix)
+			}
+			if cond.Header != nil {
+				ok = ok && headers[cond.Header.Name] == cond.Header.Exact
+				headerCount++
+			}
+		}
+		if ok && (match < 0 || prefix > matchPrefix || prefix == matchPrefix && headerCount > matchHeaders) {
+			match, matchPrefix, matchHeaders = i, prefix, headerCount
+		}
+	}
+	return match
+}
+
+var (
+	// probeHeaders are the headers of our prober's requests.
+	probeHeaders = map[string]string{
+		netheader.ProbeKey: netheader.ProbeValue,
+		netheader.HashKey:  netheader.HashValueOverride,
+	}
+	// hashHeader is the part of probeHeaders that selects the probe's path.
+	hashHeader = map[string]string{
+		netheader.HashKey: netheader.HashValueOverride,
+	}
+)
+
+func withAnnotations(annotations map[string]string) ingressOption {
+	return func(ing *v1alpha1.Ingress) {
+		ing.Annotations = annotations
+	}
+}
+
+// withTLS serves example.com with the secret secretns/secretname.
+func withTLS(ing *v1alpha1.Ingress) {
+	ing.Spec.TLS = append(ing.Spec.TLS, v1alpha1.IngressTLS{
+		Hosts:           []string{"example.com"},
+		SecretNamespace: "secretns",
+		SecretName:      "secretname",
+	})
+}
+
+func TestMakeProxiesRootProxyNamespace(t *testing.T) {
+	ing := testIngress(withAnnotations(map[string]string{ClientCASecretKey: "client-ca"}), withTLS)
+	ctx := testContext(func(cfg *config.Config) {
+		cfg.Contour.RootProxyNamespace = "contour-roots"
+	})
+
+	proxies := MakeHTTPProxies(ctx, ing, nil)
+	if len(proxies) != 2 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 2", len(proxies))
+	}
+	child, root := proxies[0], proxies[1]
+
+	if child.Namespace != "foo" || child.Spec.VirtualHost != nil || len(child.Spec.Routes) == 0 {
+		t.Errorf("child proxy = %#v, wanted a non-root proxy with routes in foo", child)
+	}
+	if _, ok := child.Labels[ParentNamespaceKey]; ok {
+		t.Errorf("child proxy has label %s", ParentNamespaceKey)
+	}
+
+	wantRoot := &v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "contour-roots",
+			Name:      kmeta.ChildName(publicClass+"-", "example.com"),
+			Labels: map[string]string{
+				GenerationKey:      "0",
+				ParentKey:          "bar",
+				ParentNamespaceKey: "foo",
+				ClassKey:           publicClass,
+				DomainHashKey:      child.Labels[DomainHashKey],
+			},
+			Annotations: map[string]string{
+				ClassKey:          publicClass,
+				ClientCASecretKey: "client-ca",
+			},
+		},
+		Spec: v1.HTTPProxySpec{
+			VirtualHost: &v1.VirtualHost{
+				Fqdn: "example.com",
+				TLS: &v1.TLS{
+					SecretName: "secretns/secretname",
+					ClientValidation: &v1.DownstreamValidation{
+						CACertificate: "foo/client-ca",
+					},
+				},
+			},
+			Includes: []v1.Include{{
+				Name:      child.Name,
+				Namespace: "foo",
+				Conditions: []v1.MatchCondition{{
+					Prefix: "/",
+				}},
+			}},
+		},
+	}
+	if !cmp.Equal(wantRoot, root) {
+		t.Error("Unexpected root proxy (-want, +got):", cmp.Diff(wantRoot, root))
+	}
+
+	// Without a class, the root proxy is named after the host alone.
+	ctx = testContext(func(cfg *config.Config) {
+		cfg.Contour.VisibilityClasses = map[v1alpha1.IngressVisibility]string{}
+		cfg.Contour.RootProxyNamespace = "contour-roots"
+	})
+	proxies = MakeHTTPProxies(ctx, ing, nil)
+	if len(proxies) != 2 {
+		t.Fatalf("len(MakeHTTPProxies) = %d, wanted 2", len(proxies))
+	}
+	if got, want := proxies[1].Name, "example.com"; got != want {
+		t.Errorf("root proxy name = %q, wanted %q", got, want)
+	}
+}
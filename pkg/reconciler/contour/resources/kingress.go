@@ -19,14 +19,17 @@ package resources
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/net-contour/pkg/reconciler/contour/resources/names"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/networking/pkg/ingress"
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 )
@@ -53,6 +56,14 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 
 	sns := ServiceNames(ctx, ing)
 
+	// Prior HTTP Proxies refer to Services in other namespaces by their bridge's name.
+	bridged := make(map[string]string)
+	for key, si := range sns {
+		if si.Namespace != ing.Namespace {
+			bridged[names.BackendBridge(ing, si.Namespace, si.Name)] = key
+		}
+	}
+
 	// Reverse engineer our previous state from the prior generation's HTTP Proxy resources.
 	for _, proxy := range previousState {
 		// Skip probe when status is not valid. It happens when the previous revision was garbage collected.
@@ -62,10 +73,10 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 			continue
 		}
 
-		// Establish the visibility based on the class annotation.
+		// Establish the visibility based on the class.
 		var vis v1alpha1.IngressVisibility
 		for v, class := range config.FromContext(ctx).Contour.VisibilityClasses {
-			if class == proxy.Annotations[ClassKey] {
+			if class == proxyClass(proxy) {
 				vis = v
 			}
 		}
@@ -74,23 +85,31 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 		}
 
 		for _, route := range proxy.Spec.Routes {
-			hasPath := false
+			httpChallenge := false
 			for _, cond := range route.Conditions {
-				if cond.Prefix != "" {
-					hasPath = true
+				if strings.Contains(cond.Prefix, HTTPChallengePath) {
+					httpChallenge = true
 				}
 			}
 			for _, svc := range route.Services {
-				si, ok := sns[svc.Name]
+				key := svc.Name
+				if k, ok := bridged[svc.Name]; ok {
+					key = k
+				}
+				si, ok := sns[key]
 				if !ok {
 					si = ServiceInfo{
+						Namespace:       ing.Namespace,
+						Name:            svc.Name,
 						Port:            intstr.FromInt(svc.Port),
 						RawVisibilities: sets.New[string](),
-						HasPath:         hasPath,
+						HTTPChallenge:   httpChallenge,
 					}
+				} else if !httpChallenge {
+					si.HTTPChallenge = false
 				}
 				si.RawVisibilities.Insert(string(vis))
-				sns[svc.Name] = si
+				sns[key] = si
 			}
 		}
 	}
@@ -102,24 +121,33 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 
 	probeHosts := make([]string, 0, len(l))
 
-	for _, name := range l {
-		si := sns[name]
-		if si.HasPath {
-			// TODO(https://github.com/knative-sandbox/net-certmanager/issues/44): Remove this.
+	for _, key := range l {
+		si := sns[key]
+		if si.HTTPChallenge {
+			// ACME solvers don't answer our probes.
 			continue
 		}
+		// Services in other namespaces are probed through their bridge Service,
+		// since the probe must not leave the kingress's namespace.
+		svcName := si.Name
+		if si.Namespace != ing.Namespace {
+			svcName = names.BackendBridge(ing, si.Namespace, si.Name)
+		}
 		for _, vis := range si.Visibilities() {
-			host := fmt.Sprintf("%s.gen-%d.%s.%s.net-contour.invalid", name, ing.Generation, ing.Name, ing.Namespace)
+			host := fmt.Sprintf("%s.gen-%d.%s.%s.net-contour.invalid", svcName, ing.Generation, ing.Name, ing.Namespace)
 			probeHosts = append(probeHosts, host)
 			childIng.Spec.Rules = append(childIng.Spec.Rules, v1alpha1.IngressRule{
 				Hosts:      []string{host},
 				Visibility: vis,
 				HTTP: &v1alpha1.HTTPIngressRuleValue{
+					// The probe path has no prefix, even for services that are
+					// only reached under one, since the prober asks for its own
+					// health check path.
 					Paths: []v1alpha1.HTTPIngressPath{{
 						RewriteHost: si.RewriteHost,
 						Splits: []v1alpha1.IngressBackendSplit{{
 							IngressBackend: v1alpha1.IngressBackend{
-								ServiceName:      name,
+								ServiceName:      svcName,
 								ServiceNamespace: ing.Namespace,
 								ServicePort:      si.Port,
 							},
@@ -132,7 +160,17 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 	}
 
 	externalIngressTLS := ing.GetIngressTLSForVisibility(v1alpha1.IngressVisibilityExternalIP)
-	hasCert := len(externalIngressTLS) > 0 || config.FromContext(ctx).Contour.DefaultTLSSecret != nil
+	if len(externalIngressTLS) == 0 {
+		// The probe hosts match no domain, so they are given the default secret
+		// of the external hosts explicitly.
+		if secret := defaultTLSSecret(ctx, ing); secret != nil {
+			externalIngressTLS = []v1alpha1.IngressTLS{{
+				SecretNamespace: secret.Namespace,
+				SecretName:      secret.Name,
+			}}
+		}
+	}
+	hasCert := len(externalIngressTLS) > 0
 
 	if ing.Spec.HTTPOption == v1alpha1.HTTPOptionRedirected && hasCert {
 		// Set the probe to operate over HTTPS IFF we have certificates AND are TLS-required
@@ -145,3 +183,21 @@ func MakeEndpointProbeIngress(ctx context.Context, ing *v1alpha1.Ingress, previo
 
 	return childIng
 }
+
+// defaultTLSSecret returns the default TLS secret of the first of the external
+// hosts of the ingress that has one, if any.
+func defaultTLSSecret(ctx context.Context, ing *v1alpha1.Ingress) *types.NamespacedName {
+	hosts := sets.New[string]()
+	for _, rule := range ing.Spec.Rules {
+		if rule.Visibility == v1alpha1.IngressVisibilityExternalIP {
+			hosts.Insert(rule.Hosts...)
+		}
+	}
+	cfg := config.FromContext(ctx).Contour
+	for _, host := range sets.List(ingress.ExpandedHosts(hosts)) {
+		if secret := cfg.DefaultTLSSecretFor(host); secret != nil {
+			return secret
+		}
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"knative.dev/net-contour/pkg/reconciler/contour/config"
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
@@ -394,6 +395,21 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			Spec: v1alpha1.IngressSpec{
 				HTTPOption: v1alpha1.HTTPOptionEnabled,
 				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"doo.gen-0.bar.foo.net-contour.invalid"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceNamespace: "foo",
+									ServiceName:      "doo",
+									ServicePort:      intstr.FromInt(124),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}, {
 					Hosts:      []string{"goo.gen-0.bar.foo.net-contour.invalid"},
 					Visibility: v1alpha1.IngressVisibilityExternalIP,
 					HTTP: &v1alpha1.HTTPIngressRuleValue{
@@ -676,7 +692,6 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 						},
 					}},
 				}, {
-					// TODO(https://github.com/knative-sandbox/net-certmanager/issues/44): Probe this once fixed.
 					Conditions: []v1.MatchCondition{{
 						Prefix: "/blah",
 					}},
@@ -708,6 +723,21 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 			Spec: v1alpha1.IngressSpec{
 				HTTPOption: v1alpha1.HTTPOptionEnabled,
 				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"blah.gen-0.bar.foo.net-contour.invalid"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceNamespace: "foo",
+									ServiceName:      "blah",
+									ServicePort:      intstr.FromInt(123),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}, {
 					Hosts:      []string{"doo.gen-0.bar.foo.net-contour.invalid"},
 					Visibility: v1alpha1.IngressVisibilityExternalIP,
 					HTTP: &v1alpha1.HTTPIngressRuleValue{
@@ -875,7 +905,6 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 						},
 					}},
 				}, {
-					// TODO(https://github.com/knative-sandbox/net-certmanager/issues/44): Probe this once fixed.
 					Conditions: []v1.MatchCondition{{
 						Prefix: "/blah",
 					}},
@@ -1003,6 +1032,220 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 				}},
 			},
 		},
+	}, {
+		name: "path-scoped services (ACME challenges skipped)",
+		ing: &v1alpha1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar",
+			},
+			Spec: v1alpha1.IngressSpec{
+				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"example.com"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Path: "/.well-known/acme-challenge/some-challenge",
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceName: "acme-http-solver",
+									ServicePort: intstr.FromInt(8089),
+								},
+								Percent: 100,
+							}},
+						}, {
+							Path: "/goo",
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceName: "goo",
+									ServicePort: intstr.FromInt(123),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}},
+			},
+		},
+		prev: []*v1.HTTPProxy{{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar-example.com",
+				Annotations: map[string]string{
+					"projectcontour.io/ingress.class": publicClass,
+				},
+			},
+			Spec: v1.HTTPProxySpec{
+				VirtualHost: &v1.VirtualHost{
+					Fqdn: "example.com",
+				},
+				Routes: []v1.Route{{
+					Conditions: []v1.MatchCondition{{
+						Prefix: "/.well-known/acme-challenge/old-challenge",
+					}},
+					Services: []v1.Service{{
+						Name: "old-acme-http-solver",
+						Port: 8089,
+					}},
+				}, {
+					Conditions: []v1.MatchCondition{{
+						Prefix: "/doo",
+					}},
+					Services: []v1.Service{{
+						Name: "doo",
+						Port: 124,
+					}},
+				}},
+			},
+			Status: v1.HTTPProxyStatus{
+				CurrentStatus: "valid",
+			},
+		}},
+		want: &v1alpha1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar--ep",
+				Annotations: map[string]string{
+					EndpointsProbeKey: "true",
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion:         "networking.internal.knative.dev/v1alpha1",
+					Kind:               "Ingress",
+					Name:               "bar",
+					Controller:         ptr.Bool(true),
+					BlockOwnerDeletion: ptr.Bool(true),
+				}},
+			},
+			Spec: v1alpha1.IngressSpec{
+				HTTPOption: v1alpha1.HTTPOptionEnabled,
+				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"doo.gen-0.bar.foo.net-contour.invalid"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceNamespace: "foo",
+									ServiceName:      "doo",
+									ServicePort:      intstr.FromInt(124),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}, {
+					Hosts:      []string{"goo.gen-0.bar.foo.net-contour.invalid"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceNamespace: "foo",
+									ServiceName:      "goo",
+									ServicePort:      intstr.FromInt(123),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}},
+			},
+		},
+	}, {
+		name: "cross-namespace split (prev through bridge)",
+		ing: &v1alpha1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "foo",
+				Name:       "bar",
+				Generation: 2,
+			},
+			Spec: v1alpha1.IngressSpec{
+				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"example.com"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceName:      "goo",
+									ServiceNamespace: "shared",
+									ServicePort:      intstr.FromInt(123),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}},
+			},
+		},
+		prev: []*v1.HTTPProxy{{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					ClassKey: privateClass,
+				},
+			},
+			Spec: v1.HTTPProxySpec{
+				Routes: []v1.Route{{
+					Services: []v1.Service{{
+						Name: "bar-goo-shared",
+						Port: 123,
+					}},
+				}},
+			},
+			Status: v1.HTTPProxyStatus{
+				CurrentStatus: "valid",
+			},
+		}},
+		want: &v1alpha1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "foo",
+				Name:      "bar--ep",
+				Annotations: map[string]string{
+					EndpointsProbeKey: "true",
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion:         "networking.internal.knative.dev/v1alpha1",
+					Kind:               "Ingress",
+					Name:               "bar",
+					Controller:         ptr.Bool(true),
+					BlockOwnerDeletion: ptr.Bool(true),
+				}},
+			},
+			Spec: v1alpha1.IngressSpec{
+				HTTPOption: v1alpha1.HTTPOptionEnabled,
+				Rules: []v1alpha1.IngressRule{{
+					Hosts:      []string{"bar-goo-shared.gen-2.bar.foo.net-contour.invalid"},
+					Visibility: v1alpha1.IngressVisibilityClusterLocal,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceNamespace: "foo",
+									ServiceName:      "bar-goo-shared",
+									ServicePort:      intstr.FromInt(123),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}, {
+					Hosts:      []string{"bar-goo-shared.gen-2.bar.foo.net-contour.invalid"},
+					Visibility: v1alpha1.IngressVisibilityExternalIP,
+					HTTP: &v1alpha1.HTTPIngressRuleValue{
+						Paths: []v1alpha1.HTTPIngressPath{{
+							Splits: []v1alpha1.IngressBackendSplit{{
+								IngressBackend: v1alpha1.IngressBackend{
+									ServiceNamespace: "foo",
+									ServiceName:      "bar-goo-shared",
+									ServicePort:      intstr.FromInt(123),
+								},
+								Percent: 100,
+							}},
+						}},
+					},
+				}},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -1014,3 +1257,136 @@ func TestMakeEndpointProbeIngress(t *testing.T) {
 		})
 	}
 }
+
+func TestMakeEndpointProbeIngressDefaultTLSSecrets(t *testing.T) {
+	ctx := (&testConfigStore{
+		config: &config.Config{
+			Contour: &config.Contour{
+				VisibilityClasses: map[v1alpha1.IngressVisibility]string{
+					v1alpha1.IngressVisibilityClusterLocal: privateClass,
+					v1alpha1.IngressVisibilityExternalIP:   publicClass,
+				},
+				DefaultTLSSecrets: map[string]types.NamespacedName{
+					"*.apps.example.com": {Namespace: "certs", Name: "apps"},
+				},
+			},
+		},
+	}).ToContext(context.Background())
+
+	tests := []struct {
+		name       string
+		host       string
+		wantOption v1alpha1.HTTPOption
+		wantTLS    []v1alpha1.IngressTLS
+	}{{
+		name:       "matching domain",
+		host:       "foo.apps.example.com",
+		wantOption: v1alpha1.HTTPOptionRedirected,
+		wantTLS: []v1alpha1.IngressTLS{{
+			Hosts:           []string{"goo.gen-0.bar.foo.net-contour.invalid"},
+			SecretNamespace: "certs",
+			SecretName:      "apps",
+		}},
+	}, {
+		name:       "no matching domain",
+		host:       "foo.example.com",
+		wantOption: v1alpha1.HTTPOptionEnabled,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ing := &v1alpha1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "foo",
+					Name:      "bar",
+				},
+				Spec: v1alpha1.IngressSpec{
+					HTTPOption: v1alpha1.HTTPOptionRedirected,
+					Rules: []v1alpha1.IngressRule{{
+						Hosts:      []string{test.host},
+						Visibility: v1alpha1.IngressVisibilityExternalIP,
+						HTTP: &v1alpha1.HTTPIngressRuleValue{
+							Paths: []v1alpha1.HTTPIngressPath{{
+								Splits: []v1alpha1.IngressBackendSplit{{
+									IngressBackend: v1alpha1.IngressBackend{
+										ServiceName: "goo",
+										ServicePort: intstr.FromInt(123),
+									},
+									Percent: 100,
+								}},
+							}},
+						},
+					}},
+				},
+			}
+
+			got := MakeEndpointProbeIngress(ctx, ing, nil)
+			if got.Spec.HTTPOption != test.wantOption {
+				t.Errorf("HTTPOption = %s, wanted %s", got.Spec.HTTPOption, test.wantOption)
+			}
+			if !cmp.Equal(test.wantTLS, got.Spec.TLS) {
+				t.Error("TLS (-want, +got) =", cmp.Diff(test.wantTLS, got.Spec.TLS))
+			}
+		})
+	}
+}
+
+func TestMakeEndpointProbeIngressIngressClassName(t *testing.T) {
+	ctx := (&testConfigStore{
+		config: &config.Config{
+			Contour: &config.Contour{
+				VisibilityClasses: map[v1alpha1.IngressVisibility]string{
+					v1alpha1.IngressVisibilityClusterLocal: privateClass,
+					v1alpha1.IngressVisibilityExternalIP:   publicClass,
+				},
+				IngressClassMode: config.IngressClassModeField,
+			},
+		},
+	}).ToContext(context.Background())
+
+	ing := &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "foo",
+			Name:       "bar",
+			Generation: 2,
+		},
+	}
+	// The previous proxy was assigned its class with Spec.IngressClassName only.
+	prev := []*v1.HTTPProxy{{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "bar-bar.foo.svc.cluster.local",
+		},
+		Spec: v1.HTTPProxySpec{
+			IngressClassName: privateClass,
+			VirtualHost:      &v1.VirtualHost{Fqdn: "bar.foo.svc.cluster.local"},
+			Routes: []v1.Route{{
+				Services: []v1.Service{{Name: "old", Port: 80}},
+			}},
+		},
+		Status: v1.HTTPProxyStatus{
+			CurrentStatus: "valid",
+		},
+	}}
+
+	got := MakeEndpointProbeIngress(ctx, ing, prev)
+	want := []v1alpha1.IngressRule{{
+		Hosts:      []string{"old.gen-2.bar.foo.net-contour.invalid"},
+		Visibility: v1alpha1.IngressVisibilityClusterLocal,
+		HTTP: &v1alpha1.HTTPIngressRuleValue{
+			Paths: []v1alpha1.HTTPIngressPath{{
+				Splits: []v1alpha1.IngressBackendSplit{{
+					IngressBackend: v1alpha1.IngressBackend{
+						ServiceNamespace: "foo",
+						ServiceName:      "old",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+				}},
+			}},
+		},
+	}}
+	if !cmp.Equal(want, got.Spec.Rules) {
+		t.Error("Rules (-want, +got) =", cmp.Diff(want, got.Spec.Rules))
+	}
+}
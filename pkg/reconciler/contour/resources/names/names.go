@@ -22,3 +22,9 @@ import "knative.dev/pkg/kmeta"
 func EndpointProbeIngress(ing kmeta.Accessor) string {
 	return kmeta.ChildName(ing.GetName()+"--", "ep")
 }
+
+// BackendBridge returns the name for the ExternalName Service that lets the
+// given kingress reach the Service name in another namespace.
+func BackendBridge(ing kmeta.Accessor, namespace, name string) string {
+	return kmeta.ChildName(ing.GetName()+"-"+name+"-", namespace)
+}
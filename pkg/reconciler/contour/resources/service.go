@@ -0,0 +1,60 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources/names"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/network"
+)
+
+// MakeBackendBridge creates an ExternalName Service in the kingress's namespace
+// that resolves to the given Service from another namespace, so that the
+// kingress's HTTPProxy resources can route to it.
+func MakeBackendBridge(ing *v1alpha1.Ingress, target *corev1.Service) *corev1.Service {
+	ports := make([]corev1.ServicePort, 0, len(target.Spec.Ports))
+	for _, port := range target.Spec.Ports {
+		ports = append(ports, corev1.ServicePort{
+			Name:        port.Name,
+			Protocol:    port.Protocol,
+			AppProtocol: port.AppProtocol,
+			Port:        port.Port,
+		})
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      names.BackendBridge(ing, target.Namespace, target.Name),
+			Namespace: ing.Namespace,
+			Labels: map[string]string{
+				ParentKey: ing.Name,
+			},
+			Annotations: map[string]string{
+				BackendServiceKey: target.Namespace + "/" + target.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(ing)},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: network.GetServiceHostname(target.Name, target.Namespace),
+			Ports:        ports,
+		},
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"knative.dev/networking/pkg/apis/networking/v1alpha1"
+	"knative.dev/pkg/ptr"
+)
+
+func TestMakeBackendBridge(t *testing.T) {
+	ing := &v1alpha1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "bar",
+		},
+	}
+	target := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "shared",
+			Name:      "goo",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports: []corev1.ServicePort{{
+				Name:       "http2",
+				Protocol:   corev1.ProtocolTCP,
+				Port:       80,
+				TargetPort: intstr.FromInt(8013),
+			}},
+		},
+	}
+
+	want := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "bar-goo-shared",
+			Labels: map[string]string{
+				ParentKey: "bar",
+			},
+			Annotations: map[string]string{
+				BackendServiceKey: "shared/goo",
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion:         "networking.internal.knative.dev/v1alpha1",
+				Kind:               "Ingress",
+				Name:               "bar",
+				Controller:         ptr.Bool(true),
+				BlockOwnerDeletion: ptr.Bool(true),
+			}},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: "goo.shared.svc.cluster.local",
+			Ports: []corev1.ServicePort{{
+				Name:     "http2",
+				Protocol: corev1.ProtocolTCP,
+				Port:     80,
+			}},
+		},
+	}
+
+	if got := MakeBackendBridge(ing, target); !cmp.Equal(want, got) {
+		t.Error("MakeBackendBridge (-want, +got) =", cmp.Diff(want, got))
+	}
+}
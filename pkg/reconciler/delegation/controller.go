@@ -0,0 +1,128 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delegation
+
+import (
+	"context"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	contourclient "knative.dev/net-contour/pkg/client/injection/client"
+	proxyinformer "knative.dev/net-contour/pkg/client/injection/informers/projectcontour/v1/httpproxy"
+	delegationinformer "knative.dev/net-contour/pkg/client/injection/informers/projectcontour/v1/tlscertificatedelegation"
+
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+)
+
+// NewController returns a new controller for the TLSCertificateDelegations that
+// let our HTTPProxies use secrets from other namespaces.
+func NewController(
+	ctx context.Context,
+	_ configmap.Watcher,
+) *controller.Impl {
+	logger := logging.FromContext(ctx)
+
+	proxyInformer := proxyinformer.Get(ctx)
+	delegationInformer := delegationinformer.Get(ctx)
+
+	c := &Reconciler{
+		contourClient:    contourclient.Get(ctx),
+		contourLister:    proxyInformer.Lister(),
+		delegationLister: delegationInformer.Lister(),
+	}
+	impl := controller.NewContext(ctx, c, controller.ControllerOptions{
+		WorkQueueName: "TLSCertificateDelegations",
+		Logger:        logger,
+	})
+
+	c.PromoteFunc = func(bkt reconciler.Bucket, enq func(reconciler.Bucket, types.NamespacedName)) error {
+		delegations, err := c.delegationLister.List(labels.SelectorFromSet(labels.Set{
+			ManagedByKey: managedByValue,
+		}))
+		if err != nil {
+			return err
+		}
+		for _, d := range delegations {
+			enq(bkt, types.NamespacedName{Namespace: d.Namespace, Name: DelegationName})
+		}
+		enq(bkt, types.NamespacedName{Namespace: system.Namespace(), Name: legacyDelegationName})
+		proxies, err := c.contourLister.List(managedProxies())
+		if err != nil {
+			return err
+		}
+		for _, proxy := range proxies {
+			for _, key := range delegationKeys(proxy) {
+				enq(bkt, key)
+			}
+		}
+		return nil
+	}
+
+	// Enqueue the delegations of the namespaces whose secrets our HTTPProxies
+	// reference, before and after they change.
+	enqueueProxy := func(obj interface{}) {
+		if proxy, ok := obj.(*v1.HTTPProxy); ok {
+			for _, key := range delegationKeys(proxy) {
+				impl.EnqueueKey(key)
+			}
+		}
+	}
+	proxyInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		// Root proxies have no owner, but carry our labels like the others.
+		FilterFunc: reconciler.LabelExistsFilterFunc(resources.ParentKey),
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: enqueueProxy,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				enqueueProxy(oldObj)
+				enqueueProxy(newObj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+				enqueueProxy(obj)
+			},
+		},
+	})
+
+	// Undo changes made to our delegations by others.
+	delegationInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterWithName(DelegationName),
+		Handler:    controller.HandleAll(impl.Enqueue),
+	})
+
+	return impl
+}
+
+// delegationKeys returns the keys of the delegations that the proxy needs.
+func delegationKeys(proxy *v1.HTTPProxy) []types.NamespacedName {
+	var keys []types.NamespacedName
+	for _, ref := range secretRefs(proxy) {
+		if ref.Namespace != proxy.Namespace {
+			keys = append(keys, types.NamespacedName{Namespace: ref.Namespace, Name: DelegationName})
+		}
+	}
+	return keys
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delegation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+
+	contourclientset "knative.dev/net-contour/pkg/client/clientset/versioned"
+	contourlisters "knative.dev/net-contour/pkg/client/listers/projectcontour/v1"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+	"knative.dev/pkg/system"
+)
+
+const (
+	// DelegationName is the name of the TLSCertificateDelegation that we keep in
+	// each namespace holding secrets referenced across namespaces by our HTTPProxies.
+	DelegationName = "knative-net-contour"
+
+	// ManagedByKey is the label that marks the TLSCertificateDelegations we manage.
+	ManagedByKey   = "app.kubernetes.io/managed-by"
+	managedByValue = "net-contour"
+
+	// legacyDelegationName is the name of the TLSCertificateDelegation that our
+	// manifests used to install in the system namespace, which delegated the
+	// routing-serving-certs secret to every namespace.  Upgrades leave it behind,
+	// so we delete it when we become the leader, once ours has taken over.
+	legacyDelegationName = "knative-serving-certs"
+	legacyProviderKey    = "networking.knative.dev/ingress-provider"
+	legacyProviderValue  = "contour"
+
+	// legacyDelegationRetryDelay is how long we wait before checking again whether
+	// our delegation has taken over the legacy one.
+	legacyDelegationRetryDelay = 5 * time.Second
+)
+
+// Reconciler keeps the TLSCertificateDelegation of a namespace in sync with the
+// secrets of that namespace that HTTPProxies in other namespaces reference.
+type Reconciler struct {
+	reconciler.LeaderAwareFuncs
+
+	contourClient contourclientset.Interface
+
+	// Listers index properties about resources
+	contourLister    contourlisters.HTTPProxyLister
+	delegationLister contourlisters.TLSCertificateDelegationLister
+}
+
+var _ controller.Reconciler = (*Reconciler)(nil)
+
+// Reconcile implements controller.Reconciler
+func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
+	logger := logging.FromContext(ctx)
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Errorf("Invalid resource key: %s", key)
+		return nil
+	}
+	if name != DelegationName && (name != legacyDelegationName || namespace != system.Namespace()) {
+		return nil
+	}
+	if !r.IsLeaderFor(types.NamespacedName{Namespace: namespace, Name: name}) {
+		return nil
+	}
+	if name == legacyDelegationName {
+		return r.deleteLegacyDelegation(ctx, namespace)
+	}
+
+	proxies, err := r.contourLister.List(managedProxies())
+	if err != nil {
+		return err
+	}
+	desired := MakeTLSCertificateDelegation(namespace, proxies)
+
+	actual, err := r.delegationLister.TLSCertificateDelegations(namespace).Get(DelegationName)
+	if apierrs.IsNotFound(err) {
+		if desired == nil {
+			return nil
+		}
+		if _, err := r.contourClient.ProjectcontourV1().TLSCertificateDelegations(namespace).Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+		logger.Infof("Created TLSCertificateDelegation %s/%s", namespace, DelegationName)
+		return nil
+	} else if err != nil {
+		return err
+	} else if actual.Labels[ManagedByKey] != managedByValue {
+		return fmt.Errorf("TLSCertificateDelegation %s/%s is not managed by net-contour", namespace, DelegationName)
+	}
+
+	if desired == nil {
+		if err := r.contourClient.ProjectcontourV1().TLSCertificateDelegations(namespace).Delete(ctx, DelegationName, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+			return err
+		}
+		logger.Infof("Deleted TLSCertificateDelegation %s/%s", namespace, DelegationName)
+		return nil
+	}
+
+	if equality.Semantic.DeepEqual(actual.Spec, desired.Spec) {
+		return nil
+	}
+	update := actual.DeepCopy()
+	update.Spec = desired.Spec
+	if _, err := r.contourClient.ProjectcontourV1().TLSCertificateDelegations(namespace).Update(ctx, update, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	logger.Infof("Updated TLSCertificateDelegation %s/%s", namespace, DelegationName)
+	return nil
+}
+
+// deleteLegacyDelegation deletes the TLSCertificateDelegation that our manifests
+// used to install, unless someone else has taken it over.
+func (r *Reconciler) deleteLegacyDelegation(ctx context.Context, namespace string) error {
+	legacy, err := r.delegationLister.TLSCertificateDelegations(namespace).Get(legacyDelegationName)
+	if apierrs.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if legacy.Labels[legacyProviderKey] != legacyProviderValue {
+		return nil
+	}
+
+	// Our HTTPProxies may rely on the legacy delegation until ours grants them
+	// the same secrets, which it does not on the first upgrade.
+	proxies, err := r.contourLister.List(managedProxies())
+	if err != nil {
+		return err
+	}
+	if desired := MakeTLSCertificateDelegation(namespace, proxies); desired != nil {
+		actual, err := r.delegationLister.TLSCertificateDelegations(namespace).Get(DelegationName)
+		if err != nil && !apierrs.IsNotFound(err) {
+			return err
+		}
+		if actual == nil || !covers(actual, desired, legacy) {
+			logging.FromContext(ctx).Infof("Keeping legacy TLSCertificateDelegation %s/%s until %s takes over",
+				namespace, legacyDelegationName, DelegationName)
+			return controller.NewRequeueAfter(legacyDelegationRetryDelay)
+		}
+	}
+
+	if err := r.contourClient.ProjectcontourV1().TLSCertificateDelegations(namespace).Delete(ctx, legacyDelegationName, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+		return err
+	}
+	logging.FromContext(ctx).Infof("Deleted legacy TLSCertificateDelegation %s/%s", namespace, legacyDelegationName)
+	return nil
+}
+
+// covers reports whether the actual delegation grants every target namespace of
+// the desired one access to the secrets that the legacy one delegates.
+func covers(actual, desired, legacy *v1.TLSCertificateDelegation) bool {
+	granted, replaced := delegatedSecrets(actual), delegatedSecrets(legacy)
+	for _, d := range desired.Spec.Delegations {
+		if _, ok := replaced[d.SecretName]; ok && !granted[d.SecretName].HasAll(d.TargetNamespaces...) {
+			return false
+		}
+	}
+	return true
+}
+
+// delegatedSecrets returns the namespaces that the delegation grants access to
+// each of its secrets.
+func delegatedSecrets(delegation *v1.TLSCertificateDelegation) map[string]sets.Set[string] {
+	secrets := make(map[string]sets.Set[string], len(delegation.Spec.Delegations))
+	for _, d := range delegation.Spec.Delegations {
+		secrets[d.SecretName] = sets.New(d.TargetNamespaces...).Union(secrets[d.SecretName])
+	}
+	return secrets
+}
+
+// MakeTLSCertificateDelegation returns the TLSCertificateDelegation that grants each
+// of the given HTTPProxies access to the secrets of the namespace it references, or
+// nil when none of them reference a secret of the namespace from another one.
+func MakeTLSCertificateDelegation(namespace string, proxies []*v1.HTTPProxy) *v1.TLSCertificateDelegation {
+	targets := make(map[string]sets.Set[string])
+	for _, proxy := range proxies {
+		for _, ref := range secretRefs(proxy) {
+			if ref.Namespace != namespace || proxy.Namespace == namespace {
+				continue
+			}
+			if _, ok := targets[ref.Name]; !ok {
+				targets[ref.Name] = sets.New[string]()
+			}
+			targets[ref.Name].Insert(proxy.Namespace)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	delegations := make([]v1.CertificateDelegation, 0, len(targets))
+	for _, secret := range sets.List(sets.KeySet(targets)) {
+		delegations = append(delegations, v1.CertificateDelegation{
+			SecretName:       secret,
+			TargetNamespaces: sets.List(targets[secret]),
+		})
+	}
+	return &v1.TLSCertificateDelegation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      DelegationName,
+			Labels: map[string]string{
+				ManagedByKey: managedByValue,
+			},
+		},
+		Spec: v1.TLSCertificateDelegationSpec{
+			Delegations: delegations,
+		},
+	}
+}
+
+// secretRefs returns the secrets referenced by the proxy that Contour only lets it
+// use from another namespace with a TLSCertificateDelegation: the certificate and
+// client validation of its virtual host, and the CA that validates its upstreams.
+func secretRefs(proxy *v1.HTTPProxy) []types.NamespacedName {
+	var names []string
+	if vhost := proxy.Spec.VirtualHost; vhost != nil && vhost.TLS != nil {
+		names = append(names, vhost.TLS.SecretName)
+		if cv := vhost.TLS.ClientValidation; cv != nil {
+			names = append(names, cv.CACertificate, cv.CertificateRevocationList)
+		}
+	}
+	for _, route := range proxy.Spec.Routes {
+		for _, svc := range route.Services {
+			if svc.UpstreamValidation != nil {
+				names = append(names, svc.UpstreamValidation.CACertificate)
+			}
+		}
+	}
+
+	refs := make([]types.NamespacedName, 0, len(names))
+	for _, name := range names {
+		// Secrets without a namespace are in the proxy's own.
+		if ns, name, ok := strings.Cut(name, "/"); ok {
+			refs = append(refs, types.NamespacedName{Namespace: ns, Name: name})
+		}
+	}
+	return refs
+}
+
+// managedProxies selects the HTTPProxies that we create for KIngresses.
+func managedProxies() labels.Selector {
+	req, _ := labels.NewRequirement(resources.ParentKey, selection.Exists, nil)
+	return labels.NewSelector().Add(*req)
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2026 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delegation
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/projectcontour/contour/apis/projectcontour/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+
+	fakecontourclient "knative.dev/net-contour/pkg/client/injection/client/fake"
+	"knative.dev/net-contour/pkg/reconciler/contour/resources"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/system"
+
+	_ "knative.dev/pkg/system/testing"
+
+	. "knative.dev/net-contour/pkg/reconciler/testing"
+	. "knative.dev/pkg/reconciler/testing"
+)
+
+const secretNamespace = "knative-serving"
+
+var delegationKey = secretNamespace + "/" + DelegationName
+
+func TestReconcile(t *testing.T) {
+	table := TableTest{{
+		Name: "bad workqueue key",
+		Key:  "too/many/parts",
+	}, {
+		Name: "not our delegation",
+		Key:  secretNamespace + "/other",
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert")),
+		},
+	}, {
+		Name: "no cross-namespace secrets",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			proxy(secretNamespace, withSecret(secretNamespace+"/default-cert")),
+			proxy("a", withSecret("own-cert")),
+		},
+	}, {
+		Name: "create delegation",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert"), withUpstreamCA(secretNamespace+"/routing-serving-certs")),
+			proxy("b", withSecret(secretNamespace+"/default-cert")),
+			proxy("c", withSecret("other-ns/cert")),
+		},
+		WantCreates: []runtime.Object{
+			delegation(v1.CertificateDelegation{
+				SecretName:       "default-cert",
+				TargetNamespaces: []string{"a", "b"},
+			}, v1.CertificateDelegation{
+				SecretName:       "routing-serving-certs",
+				TargetNamespaces: []string{"a"},
+			}),
+		},
+	}, {
+		Name: "client validation secrets",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			proxy("a", withSecret("own-cert"), withClientValidation(secretNamespace+"/client-ca", secretNamespace+"/client-crl")),
+		},
+		WantCreates: []runtime.Object{
+			delegation(v1.CertificateDelegation{
+				SecretName:       "client-ca",
+				TargetNamespaces: []string{"a"},
+			}, v1.CertificateDelegation{
+				SecretName:       "client-crl",
+				TargetNamespaces: []string{"a"},
+			}),
+		},
+	}, {
+		Name: "proxies we do not manage are ignored",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert"), func(p *v1.HTTPProxy) {
+				p.Labels = nil
+			}),
+		},
+	}, {
+		Name: "narrow delegation",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert")),
+			delegation(v1.CertificateDelegation{
+				SecretName:       "default-cert",
+				TargetNamespaces: []string{"*"},
+			}),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: delegation(v1.CertificateDelegation{
+				SecretName:       "default-cert",
+				TargetNamespaces: []string{"a"},
+			}),
+		}},
+	}, {
+		Name: "delegation up to date",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert")),
+			delegation(v1.CertificateDelegation{
+				SecretName:       "default-cert",
+				TargetNamespaces: []string{"a"},
+			}),
+		},
+	}, {
+		Name: "delete delegation",
+		Key:  delegationKey,
+		Objects: []runtime.Object{
+			delegation(v1.CertificateDelegation{
+				SecretName:       "default-cert",
+				TargetNamespaces: []string{"a"},
+			}),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: secretNamespace,
+				Resource:  v1.SchemeGroupVersion.WithResource("tlscertificatedelegations"),
+			},
+			Name: DelegationName,
+		}},
+	}, {
+		Name:    "delegation not managed by us",
+		Key:     delegationKey,
+		WantErr: true,
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert")),
+			func() runtime.Object {
+				d := delegation(v1.CertificateDelegation{
+					SecretName:       "default-cert",
+					TargetNamespaces: []string{"*"},
+				})
+				d.Labels = nil
+				return d
+			}(),
+		},
+	}, {
+		Name: "delete legacy delegation",
+		Key:  system.Namespace() + "/" + legacyDelegationName,
+		Objects: []runtime.Object{
+			legacyDelegation(system.Namespace(), map[string]string{legacyProviderKey: legacyProviderValue}),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: system.Namespace(),
+				Resource:  v1.SchemeGroupVersion.WithResource("tlscertificatedelegations"),
+			},
+			Name: legacyDelegationName,
+		}},
+	}, {
+		Name: "legacy delegation still in use",
+		Key:  system.Namespace() + "/" + legacyDelegationName,
+		// Requeued until our delegation takes over.
+		WantErr: true,
+		Objects: []runtime.Object{
+			legacyDelegation(system.Namespace(), map[string]string{legacyProviderKey: legacyProviderValue}),
+			proxy("a", withUpstreamCA(system.Namespace()+"/routing-serving-certs")),
+		},
+	}, {
+		Name:    "legacy delegation not fully replaced",
+		Key:     system.Namespace() + "/" + legacyDelegationName,
+		WantErr: true,
+		Objects: []runtime.Object{
+			legacyDelegation(system.Namespace(), map[string]string{legacyProviderKey: legacyProviderValue}),
+			proxy("a", withUpstreamCA(system.Namespace()+"/routing-serving-certs")),
+			proxy("b", withUpstreamCA(system.Namespace()+"/routing-serving-certs")),
+			inSystemNamespace(delegation(v1.CertificateDelegation{
+				SecretName:       "routing-serving-certs",
+				TargetNamespaces: []string{"a"},
+			})),
+		},
+	}, {
+		Name: "legacy delegation replaced",
+		Key:  system.Namespace() + "/" + legacyDelegationName,
+		Objects: []runtime.Object{
+			legacyDelegation(system.Namespace(), map[string]string{legacyProviderKey: legacyProviderValue}),
+			proxy("a", withUpstreamCA(system.Namespace()+"/routing-serving-certs")),
+			inSystemNamespace(delegation(v1.CertificateDelegation{
+				SecretName:       "routing-serving-certs",
+				TargetNamespaces: []string{"a"},
+			})),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: system.Namespace(),
+				Resource:  v1.SchemeGroupVersion.WithResource("tlscertificatedelegations"),
+			},
+			Name: legacyDelegationName,
+		}},
+	}, {
+		Name: "legacy delegation already deleted",
+		Key:  system.Namespace() + "/" + legacyDelegationName,
+	}, {
+		Name: "legacy delegation taken over",
+		Key:  system.Namespace() + "/" + legacyDelegationName,
+		Objects: []runtime.Object{
+			legacyDelegation(system.Namespace(), nil),
+		},
+	}, {
+		Name: "legacy delegation name outside the system namespace",
+		Key:  secretNamespace + "/" + legacyDelegationName,
+		Objects: []runtime.Object{
+			legacyDelegation(secretNamespace, map[string]string{legacyProviderKey: legacyProviderValue}),
+		},
+	}, {
+		Name:    "failure creating delegation",
+		Key:     delegationKey,
+		WantErr: true,
+		WithReactors: []clientgotesting.ReactionFunc{
+			InduceFailure("create", "tlscertificatedelegations"),
+		},
+		Objects: []runtime.Object{
+			proxy("a", withSecret(secretNamespace+"/default-cert")),
+		},
+		WantCreates: []runtime.Object{
+			delegation(v1.CertificateDelegation{
+				SecretName:       "default-cert",
+				TargetNamespaces: []string{"a"},
+			}),
+		},
+	}}
+
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, _ configmap.Watcher) controller.Reconciler {
+		return &Reconciler{
+			contourClient:    fakecontourclient.Get(ctx),
+			contourLister:    listers.GetHTTPProxyLister(),
+			delegationLister: listers.GetTLSCertificateDelegationLister(),
+		}
+	}))
+}
+
+func legacyDelegation(namespace string, labels map[string]string) *v1.TLSCertificateDelegation {
+	return &v1.TLSCertificateDelegation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      legacyDelegationName,
+			Labels:    labels,
+		},
+		Spec: v1.TLSCertificateDelegationSpec{
+			Delegations: []v1.CertificateDelegation{{
+				SecretName:       "routing-serving-certs",
+				TargetNamespaces: []string{"*"},
+			}},
+		},
+	}
+}
+
+type proxyOption func(*v1.HTTPProxy)
+
+func proxy(namespace string, opts ...proxyOption) *v1.HTTPProxy {
+	p := &v1.HTTPProxy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      "name-example.com",
+			Labels: map[string]string{
+				resources.ParentKey: "name",
+			},
+		},
+		Spec: v1.HTTPProxySpec{
+			VirtualHost: &v1.VirtualHost{Fqdn: "example.com"},
+			Routes: []v1.Route{{
+				Services: []v1.Service{{Name: "goo", Port: 80}},
+			}},
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func withSecret(name string) proxyOption {
+	return func(p *v1.HTTPProxy) {
+		p.Spec.VirtualHost.TLS = &v1.TLS{SecretName: name}
+	}
+}
+
+func withClientValidation(ca, crl string) proxyOption {
+	return func(p *v1.HTTPProxy) {
+		p.Spec.VirtualHost.TLS.ClientValidation = &v1.DownstreamValidation{
+			CACertificate:             ca,
+			CertificateRevocationList: crl,
+		}
+	}
+}
+
+func withUpstreamCA(name string) proxyOption {
+	return func(p *v1.HTTPProxy) {
+		p.Spec.Routes[0].Services[0].UpstreamValidation = &v1.UpstreamValidation{
+			CACertificate: name,
+			SubjectName:   "kn-user-" + p.Namespace,
+		}
+	}
+}
+
+func delegation(delegations ...v1.CertificateDelegation) *v1.TLSCertificateDelegation {
+	return &v1.TLSCertificateDelegation{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: secretNamespace,
+			Name:      DelegationName,
+			Labels: map[string]string{
+				ManagedByKey: managedByValue,
+			},
+		},
+		Spec: v1.TLSCertificateDelegationSpec{
+			Delegations: delegations,
+		},
+	}
+}
+
+func inSystemNamespace(d *v1.TLSCertificateDelegation) *v1.TLSCertificateDelegation {
+	d.Namespace = system.Namespace()
+	return d
+}